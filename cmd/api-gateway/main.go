@@ -1,11 +1,13 @@
 package main
 
 import (
+	_ "embed"
 	"log"
 	"net/http"
 	"net/url"
 
 	"news/pkg/config"
+	"news/pkg/jwt"
 	myMiddleware "news/pkg/middleware"
 
 	"github.com/labstack/echo-contrib/echoprometheus"
@@ -14,12 +16,21 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// openAPISpec описывает все маршруты, зарегистрированные в setRoutes.
+// При добавлении маршрута его нужно добавить и в openapi.json — это проверяет TestOpenAPISpecCoversRoutes.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+//go:embed docs.html
+var docsPage []byte
+
 type Config struct {
 	Port              string
 	AuthServiceURL    string
 	ArticleServiceURL string
 	RedisURL          string
-	JWTSecret         string
+	JWKSURL           string
 }
 
 type ServiceProxy struct {
@@ -69,31 +80,99 @@ func (g *APIGateway) setRoutes() {
 	authPages := g.echo.Group("")
 	authPages.GET("/login-page", g.proxyToAuthService)
 	authPages.GET("/register-page", g.proxyToAuthService)
+	authPages.GET("/forgot-password", g.proxyToAuthService)
+	authPages.GET("/reset-password", g.proxyToAuthService)
 
 	// Группа страниц статей
 	articlePages := g.echo.Group("")
 	articlePages.Use(myMiddleware.JWTAuth)
 	articlePages.GET("/add-article-page", g.proxyToArticleService)
 	articlePages.GET("/search", g.proxyToArticleService)
-	articlePages.GET("/article/:article_id", g.proxyToArticleService)
+
+	// Опубликованные статьи открыты без входа, в том числе для поисковых роботов из карты сайта
+	g.echo.GET("/article/:article_id", g.proxyToArticleService, myMiddleware.JWTOptional)
 
 	// Public API routes
 	public := g.echo.Group("")
 	public.POST("/login", g.proxyToAuthService)
 	public.POST("/register", g.proxyToAuthService)
 	public.POST("/logout", g.proxyToAuthService)
+	public.GET("/token/refresh", g.proxyToAuthService)
+	public.POST("/token/refresh", g.proxyToAuthService)
+	public.GET("/.well-known/jwks.json", g.proxyToAuthService)
+	public.POST("/forgot-password", g.proxyToAuthService)
+	public.POST("/reset-password", g.proxyToAuthService)
 	public.GET("/get-info/user-info", g.proxyToAuthService)
 	public.GET("/popular-news", g.proxyToArticleService)
+	public.GET("/tags", g.proxyToArticleService)
+	public.GET("/tags/:name", g.proxyToArticleService)
+	public.GET("/feed.rss", g.proxyToArticleService)
+	public.GET("/feed.atom", g.proxyToArticleService)
+	public.GET("/tags/:name/feed.rss", g.proxyToArticleService)
+	public.GET("/tags/:name/feed.atom", g.proxyToArticleService)
+	public.GET("/users/:user_id", g.proxyToArticleService)
+	public.GET("/users/:user_id/feed.rss", g.proxyToArticleService)
+	public.GET("/users/:user_id/feed.atom", g.proxyToArticleService)
+	public.GET("/sitemap.xml", g.proxyToArticleService)
+	public.GET("/sitemaps/:page", g.proxyToArticleService)
+	public.GET("/uploads/*", g.proxyToArticleService)
 
 	// Protected API routes
 	protected := g.echo.Group("")
 	protected.Use(myMiddleware.JWTAuth)
+	protected.POST("/logout-all", g.proxyToAuthService)
+	protected.GET("/account", g.proxyToAuthService)
+	protected.POST("/account/password", g.proxyToAuthService)
+	protected.POST("/account/email", g.proxyToAuthService)
+	protected.GET("/sessions", g.proxyToAuthService)
+	protected.DELETE("/sessions/:session_id", g.proxyToAuthService)
+	protected.POST("/sessions/:session_id/delete", g.proxyToAuthService)
 	protected.POST("/add-article", g.proxyToArticleService)
 	protected.POST("/article/delete/:article_id", g.proxyToArticleService)
 	protected.POST("/articles", g.proxyToArticleService)
 	protected.PUT("/articles/:id", g.proxyToArticleService)
+	protected.POST("/articles/:id/status", g.proxyToArticleService)
+	protected.GET("/articles/:id/revisions", g.proxyToArticleService)
+	protected.GET("/articles/:id/revisions/diff", g.proxyToArticleService)
+	protected.POST("/articles/:id/revisions/:revision_id/restore", g.proxyToArticleService)
 	protected.GET("/popular-news", g.proxyToArticleService)
 	protected.GET("/article/search", g.proxyToArticleService)
+	protected.GET("/article/:article_id/comments", g.proxyToArticleService)
+	protected.POST("/article/:article_id/comments", g.proxyToArticleService)
+	protected.POST("/article/:article_id/reactions/:kind", g.proxyToArticleService)
+	protected.POST("/article/:article_id/bookmark", g.proxyToArticleService)
+	protected.DELETE("/article/:article_id/bookmark", g.proxyToArticleService)
+	protected.POST("/article/:article_id/bookmark/delete", g.proxyToArticleService)
+	protected.GET("/bookmarks", g.proxyToArticleService)
+	protected.POST("/attachments", g.proxyToArticleService)
+	protected.DELETE("/attachments/:attachment_id", g.proxyToArticleService)
+	protected.POST("/attachments/:attachment_id/delete", g.proxyToArticleService)
+	protected.POST("/users/:user_id/follow", g.proxyToArticleService)
+	protected.DELETE("/users/:user_id/follow", g.proxyToArticleService)
+	protected.POST("/users/:user_id/follow/delete", g.proxyToArticleService)
+	protected.GET("/feed", g.proxyToArticleService)
+	protected.POST("/tags/:name/follow", g.proxyToArticleService)
+	protected.DELETE("/tags/:name/follow", g.proxyToArticleService)
+	protected.POST("/tags/:name/follow/delete", g.proxyToArticleService)
+	protected.POST("/admin/tags/merge", g.proxyToArticleService)
+	protected.POST("/admin/tags/aliases", g.proxyToArticleService)
+	protected.PUT("/comments/:comment_id", g.proxyToArticleService)
+	protected.DELETE("/comments/:comment_id", g.proxyToArticleService)
+	protected.POST("/comments/:comment_id/delete", g.proxyToArticleService)
+	protected.POST("/comments/:comment_id/hide", g.proxyToArticleService)
+	protected.POST("/comments/:comment_id/unhide", g.proxyToArticleService)
+
+	// JSON API v1
+	apiPublic := g.echo.Group("/api/v1")
+	apiPublic.GET("/articles", g.proxyToArticleService)
+	apiPublic.GET("/articles/search", g.proxyToArticleService)
+	apiPublic.GET("/articles/:id", g.proxyToArticleService)
+
+	apiProtected := g.echo.Group("/api/v1")
+	apiProtected.Use(myMiddleware.JWTAuthAPI)
+	apiProtected.POST("/articles", g.proxyToArticleService)
+	apiProtected.PUT("/articles/:id", g.proxyToArticleService)
+	apiProtected.DELETE("/articles/:id", g.proxyToArticleService)
 }
 
 func (g *APIGateway) proxyToArticleService(c echo.Context) error {
@@ -125,17 +204,14 @@ func (g *APIGateway) proxyToService(serviceName string) echo.HandlerFunc {
 }
 
 func main() {
+	config.LoadConfig()
 	cfg := &Config{
 		Port:              config.GetEnv("PORT", "8080"),
 		AuthServiceURL:    config.GetEnv("AUTH_SERVICE_URL", "http://auth-service:8080"),
 		ArticleServiceURL: config.GetEnv("ARTICLE_SERVICE_URL", "http://article-service:8080"),
 		RedisURL:          config.GetEnv("REDIS_URL", "redis:6379"),
-		JWTSecret:         config.GetEnv("JWT_SECRET", ""),
-	}
-
-	if cfg.JWTSecret == "" {
-		log.Fatal("JWT_SECRET environment variable is required")
 	}
+	cfg.JWKSURL = config.GetEnv("JWKS_URL", cfg.AuthServiceURL+"/.well-known/jwks.json")
 	gateway := NewAPIGateway(cfg)
 
 	defer gateway.echo.Close()
@@ -162,11 +238,19 @@ func NewAPIGateway(cfg *Config) *APIGateway {
 	e.GET("/health", func(c echo.Context) error {
 		return c.JSON(200, map[string]string{"status": "healthy"})
 	})
+	e.GET("/openapi.json", func(c echo.Context) error {
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, openAPISpec)
+	})
+	e.GET("/docs", func(c echo.Context) error {
+		return c.HTMLBlob(http.StatusOK, docsPage)
+	})
 	redisOpts, err := redis.ParseURL(cfg.RedisURL)
 	if err != nil {
 		log.Fatal("Failed to parse redis url:", err)
 	}
 	redisClient := redis.NewClient(redisOpts)
+	myMiddleware.SetRevocationStore(redisClient)
+	jwt.SetKeySource(jwt.NewJWKSCache(cfg.JWKSURL))
 	gateway := &APIGateway{
 		config:   cfg,
 		echo:     e,
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+var echoParam = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+
+	g := &APIGateway{
+		config:   &Config{},
+		echo:     echo.New(),
+		services: make(map[string]*ServiceProxy),
+	}
+	g.setRoutes()
+
+	methods := map[string]bool{
+		http.MethodGet: true, http.MethodPost: true, http.MethodPut: true,
+		http.MethodPatch: true, http.MethodDelete: true,
+	}
+	for _, route := range g.echo.Routes() {
+		if !methods[route.Method] {
+			continue
+		}
+		path := echoParam.ReplaceAllString(route.Path, "{$1}")
+		// Маршрут с * отдаёт вложенные пути, в спецификации это параметр {path}
+		path = strings.Replace(path, "*", "{path}", 1)
+		if _, ok := spec.Paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("route %s %s is missing from openapi.json", route.Method, path)
+		}
+	}
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
 	"html/template"
 	"io"
 	"log"
 	"net/http"
 	articleHandler "news/internal/article/handler"
+	articleService "news/internal/article/service"
+	"news/pkg/config"
 	"news/pkg/database"
+	"news/pkg/jwt"
+	"news/pkg/storage"
 	"os"
+	"strconv"
+	"time"
 
 	"news/pkg/middleware"
 
@@ -25,12 +32,50 @@ func (t *TemplateRegistry) Render(w io.Writer, name string, data interface{}, c
 }
 
 func main() {
-
+	cfg := config.LoadConfig()
+	if cfg.SiteURL == "" {
+		log.Fatal("SITE_URL is required: feeds and sitemaps need the public site address")
+	}
 	err := database.InitDB()
 	if err != nil {
 		log.Printf("error init database: %s", err)
 		log.Fatal(err)
 	}
+	if err := articleService.BackfillArticleSlugs(database.DB); err != nil {
+		log.Printf("error backfilling article slugs: %s", err)
+	}
+	if err := articleService.BackfillArticleHTML(database.DB); err != nil {
+		log.Printf("error rendering article HTML: %s", err)
+	}
+	if err := articleService.BackfillPublishedAt(database.DB); err != nil {
+		log.Printf("error backfilling article publish times: %s", err)
+	}
+	if err := articleService.BackfillTagNames(database.DB); err != nil {
+		log.Printf("error normalizing tag names: %s", err)
+	}
+	if err := database.InitRedis(); err != nil {
+		log.Printf("error init redis: %s", err)
+	}
+	articleHandler.SetRedisClient(database.Redis)
+	articleHandler.SetSiteURL(cfg.SiteURL)
+	middleware.SetRevocationStore(database.Redis)
+	jwt.SetKeySource(jwt.NewJWKSCache(cfg.JWKSURL))
+	viewWindow, err := time.ParseDuration(config.GetEnv("VIEW_DEDUP_WINDOW", "30m"))
+	if err != nil {
+		log.Fatalf("invalid VIEW_DEDUP_WINDOW: %s", err)
+	}
+	articleHandler.ViewDedupWindow = viewWindow
+	maxUploadBytes, err := strconv.ParseInt(config.GetEnv("UPLOAD_MAX_BYTES", "5242880"), 10, 64)
+	if err != nil || maxUploadBytes <= 0 {
+		log.Fatalf("invalid UPLOAD_MAX_BYTES: %s", config.GetEnv("UPLOAD_MAX_BYTES", ""))
+	}
+	articleHandler.MaxUploadBytes = maxUploadBytes
+	uploadDir := config.GetEnv("UPLOAD_DIR", "./uploads")
+	uploadStorage, err := storage.NewLocalStorage(uploadDir, config.GetEnv("UPLOAD_BASE_URL", "/uploads"))
+	if err != nil {
+		log.Fatalf("error init upload storage: %s", err)
+	}
+	articleHandler.SetAttachmentStorage(uploadStorage)
 	e := echo.New()
 
 	e.Use(echoprometheus.NewMiddleware("article_service"))
@@ -82,16 +127,85 @@ func main() {
 	e.Use(middleware.CheckTimeForResp)
 	protected.Use(middleware.JWTAuth)
 	e.GET("/popular-news", articleHandler.AllArticle)
+	e.GET("/tags", articleHandler.GetTags)
+	e.GET("/tags/:name", articleHandler.GetTagArticles)
+	e.GET("/feed.rss", articleHandler.GetSiteFeed)
+	e.GET("/feed.atom", articleHandler.GetSiteFeed)
+	e.GET("/tags/:name/feed.rss", articleHandler.GetTagFeed)
+	e.GET("/tags/:name/feed.atom", articleHandler.GetTagFeed)
+	e.GET("/users/:user_id", articleHandler.GetAuthorArticles)
+	e.GET("/users/:user_id/feed.rss", articleHandler.GetAuthorFeed)
+	e.GET("/users/:user_id/feed.atom", articleHandler.GetAuthorFeed)
+	e.GET("/sitemap.xml", articleHandler.GetSitemapIndex)
+	e.GET("/sitemaps/:page", articleHandler.GetSitemapPage)
+	e.GET("/article/:article_id", articleHandler.GetArticle, middleware.JWTOptional)
+	e.GET(uploadStorage.BaseURL+"/*", articleHandler.ServeAttachment, middleware.JWTOptional)
 	protected.GET("/add-article-page", func(c echo.Context) error {
 		return c.File("/root/web/templates/addArticle.html")
 	})
 	e.POST("/add-article", articleHandler.AddArticle)
-	protected.GET("/article/:article_id", articleHandler.GetArticle)
+	protected.POST("/articles", articleHandler.AddArticle)
 	protected.POST("/article/delete/:article_id", articleHandler.DeleteArticle)
+	protected.PUT("/articles/:id", articleHandler.UpdateArticle)
+	protected.POST("/articles/:id/status", articleHandler.ChangeArticleStatus)
+	protected.GET("/articles/:id/revisions", articleHandler.GetArticleRevisions)
+	protected.GET("/articles/:id/revisions/diff", articleHandler.DiffArticleRevisions)
+	protected.POST("/articles/:id/revisions/:revision_id/restore", articleHandler.RestoreArticleRevision)
 	protected.GET("/article/search", articleHandler.SearchArticles)
+	protected.GET("/article/:article_id/comments", articleHandler.GetComments)
+	protected.POST("/article/:article_id/comments", articleHandler.AddComment)
+	protected.POST("/article/:article_id/reactions/:kind", articleHandler.ToggleReaction)
+	protected.POST("/article/:article_id/bookmark", articleHandler.AddBookmark)
+	protected.DELETE("/article/:article_id/bookmark", articleHandler.RemoveBookmark)
+	protected.POST("/article/:article_id/bookmark/delete", articleHandler.RemoveBookmark)
+	protected.GET("/bookmarks", articleHandler.GetBookmarks)
+	protected.POST("/attachments", articleHandler.UploadAttachment)
+	protected.DELETE("/attachments/:attachment_id", articleHandler.DeleteAttachment)
+	protected.POST("/attachments/:attachment_id/delete", articleHandler.DeleteAttachment)
+	protected.POST("/users/:user_id/follow", articleHandler.FollowAuthor)
+	protected.DELETE("/users/:user_id/follow", articleHandler.UnfollowAuthor)
+	protected.POST("/users/:user_id/follow/delete", articleHandler.UnfollowAuthor)
+	protected.GET("/feed", articleHandler.GetFeed)
+	protected.POST("/tags/:name/follow", articleHandler.FollowTag)
+	protected.DELETE("/tags/:name/follow", articleHandler.UnfollowTag)
+	protected.POST("/tags/:name/follow/delete", articleHandler.UnfollowTag)
+	admin := protected.Group("/admin", middleware.RequireAdmin)
+	admin.POST("/tags/merge", articleHandler.MergeTags)
+	admin.POST("/tags/aliases", articleHandler.AddTagAlias)
+	protected.PUT("/comments/:comment_id", articleHandler.UpdateComment)
+	protected.DELETE("/comments/:comment_id", articleHandler.DeleteComment)
+	protected.POST("/comments/:comment_id/delete", articleHandler.DeleteComment)
+	protected.POST("/comments/:comment_id/hide", articleHandler.HideComment)
+	protected.POST("/comments/:comment_id/unhide", articleHandler.ShowComment)
 	protected.GET("/search", func(c echo.Context) error {
 		return c.File("/root/web/templates/search.html")
 	})
+
+	api := e.Group("/api/v1")
+	api.GET("/articles", articleHandler.APIListArticles)
+	api.GET("/articles/search", articleHandler.APISearchArticles)
+	api.GET("/articles/:id", articleHandler.APIGetArticle)
+	apiProtected := api.Group("")
+	apiProtected.Use(middleware.JWTAuthAPI)
+	apiProtected.POST("/articles", articleHandler.APICreateArticle)
+	apiProtected.PUT("/articles/:id", articleHandler.APIUpdateArticle)
+	apiProtected.DELETE("/articles/:id", articleHandler.APIDeleteArticle)
+	publishInterval, err := time.ParseDuration(config.GetEnv("PUBLISH_INTERVAL", "30s"))
+	if err != nil {
+		log.Fatalf("invalid PUBLISH_INTERVAL: %s", err)
+	}
+	go articleService.RunScheduledPublisher(context.Background(), database.DB, publishInterval, articleHandler.OnArticlesPublished)
+	viewFlushInterval, err := time.ParseDuration(config.GetEnv("VIEW_FLUSH_INTERVAL", "1m"))
+	if err != nil {
+		log.Fatalf("invalid VIEW_FLUSH_INTERVAL: %s", err)
+	}
+	go articleService.RunViewFlusher(context.Background(), database.DB, database.Redis, viewFlushInterval, articleHandler.InvalidateArticlesCache)
+	trendingInterval, err := time.ParseDuration(config.GetEnv("TRENDING_INTERVAL", "5m"))
+	if err != nil {
+		log.Fatalf("invalid TRENDING_INTERVAL: %s", err)
+	}
+	go articleService.RunTrendingUpdater(context.Background(), database.DB, database.Redis, trendingInterval)
+
 	go func() {
 		metrics := echo.New()
 		metrics.GET("/metrics", echoprometheus.NewHandler())
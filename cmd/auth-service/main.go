@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"html/template"
 	"io"
 	"log"
 	"net/http"
 	authHandler "news/internal/auth/handler"
+	"news/pkg/config"
 	"news/pkg/database"
+	"news/pkg/jwt"
+	"news/pkg/mailer"
 	"os"
+	"time"
 
 	"news/pkg/middleware"
 	"news/pkg/models"
@@ -26,11 +31,38 @@ func (t *TemplateRegistry) Render(w io.Writer, name string, data interface{}, c
 }
 
 func main() {
+	cfg := config.LoadConfig()
+	if cfg.SiteURL == "" {
+		log.Fatal("SITE_URL is required: password reset emails need the public site address")
+	}
 	err := database.InitDB()
 	if err != nil {
 		log.Printf("error init database: %s", err)
 		log.Fatal(err)
 	}
+	if err := database.InitRedis(); err != nil {
+		log.Printf("error init redis: %s", err)
+	}
+	authHandler.SetRedisClient(database.Redis)
+	authHandler.SetSiteURL(cfg.SiteURL)
+	middleware.SetRevocationStore(database.Redis)
+	keyRotation, err := time.ParseDuration(cfg.JWTKeyRotation)
+	if err != nil || keyRotation <= 0 {
+		log.Fatalf("invalid JWT_KEY_ROTATION: %s", cfg.JWTKeyRotation)
+	}
+	keyManager, err := jwt.NewKeyManager(cfg.JWTKeysDir, keyRotation)
+	if err != nil {
+		log.Fatalf("error loading signing keys: %s", err)
+	}
+	jwt.SetSigner(keyManager)
+	jwt.SetKeySource(keyManager)
+	go keyManager.Run(context.Background())
+	authHandler.SetMailer(mailer.New(
+		config.GetEnv("SMTP_ADDR", ""),
+		config.GetEnv("SMTP_FROM", "noreply@localhost"),
+		config.GetEnv("SMTP_USERNAME", ""),
+		config.GetEnv("SMTP_PASSWORD", ""),
+	))
 	e := echo.New()
 
 	e.Use(echoprometheus.NewMiddleware("auth_service"))
@@ -94,6 +126,10 @@ func main() {
 	e.GET("/health", func(c echo.Context) error {
 		return c.JSON(200, map[string]string{"status": "healthy"})
 	})
+	e.GET("/.well-known/jwks.json", func(c echo.Context) error {
+		c.Response().Header().Set("Cache-Control", "public, max-age=300")
+		return c.JSON(http.StatusOK, keyManager.JWKS())
+	})
 
 	e.GET("/", func(c echo.Context) error {
 		return c.File("/root/web/templates/index.html")
@@ -108,8 +144,21 @@ func main() {
 	e.POST("/login", authHandler.Login)
 	e.POST("/register", authHandler.Register)
 	e.POST("/logout", authHandler.Logout)
+	e.GET("/token/refresh", authHandler.RefreshToken)
+	e.POST("/token/refresh", authHandler.RefreshToken)
+	e.GET("/forgot-password", authHandler.ForgotPasswordPage)
+	e.POST("/forgot-password", authHandler.ForgotPassword)
+	e.GET("/reset-password", authHandler.ResetPasswordPage)
+	e.POST("/reset-password", authHandler.ResetPassword)
 	protected := e.Group("")
-	protected.Use(middleware.JWTAuth)
+	protected.Use(middleware.JWTAuth, middleware.CSRF)
+	protected.POST("/logout-all", authHandler.LogoutAll)
+	protected.GET("/account", authHandler.AccountPage)
+	protected.POST("/account/password", authHandler.ChangePassword)
+	protected.POST("/account/email", authHandler.UpdateEmail)
+	protected.GET("/sessions", authHandler.ListSessions)
+	protected.DELETE("/sessions/:session_id", authHandler.RevokeSession)
+	protected.POST("/sessions/:session_id/delete", authHandler.RevokeSession)
 	go func() {
 		metrics := echo.New()
 		metrics.GET("/metrics", echoprometheus.NewHandler())
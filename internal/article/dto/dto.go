@@ -0,0 +1,146 @@
+// Package dto описывает структуры JSON API статей. Они отделены от моделей GORM,
+// чтобы в ответ не попадали служебные поля и связи, такие как данные пользователя.
+package dto
+
+import (
+	"news/internal/article/service"
+	"news/pkg/models"
+	"strings"
+	"time"
+)
+
+type Author struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+type Article struct {
+	ID      uint   `json:"id"`
+	Slug    string `json:"slug"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	// ContentHTML — содержимое, переведённое из Markdown в очищенный HTML
+	ContentHTML string           `json:"content_html,omitempty"`
+	Status      string           `json:"status"`
+	PublishAt   *time.Time       `json:"publish_at,omitempty"`
+	PublishedAt *time.Time       `json:"published_at,omitempty"`
+	NumViews    int              `json:"num_views"`
+	Author      Author           `json:"author"`
+	Tags        []string         `json:"tags"`
+	Reactions   map[string]int64 `json:"reactions"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	// Related — похожие статьи, заполняется только при получении одной статьи
+	Related []Article `json:"related,omitempty"`
+}
+
+type ArticleList struct {
+	Articles   []Article `json:"articles"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	Total      int64     `json:"total"`
+}
+
+// ArticleInput — тело запроса на создание или изменение статьи.
+type ArticleInput struct {
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Tags      []string   `json:"tags"`
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+type Error struct {
+	Error string `json:"error"`
+}
+
+// TagString возвращает теги в формате, который принимает сервис статей.
+func (in ArticleInput) TagString() string {
+	return strings.Join(in.Tags, ",")
+}
+
+func FromArticle(a models.Article) Article {
+	tags := make([]string, 0, len(a.Tags))
+	for _, tag := range a.Tags {
+		tags = append(tags, tag.TagContent)
+	}
+	reactions := a.Reactions
+	if reactions == nil {
+		reactions = map[string]int64{}
+	}
+	return Article{
+		ID:          a.ID,
+		Slug:        a.Slug,
+		Title:       a.ArticleTitle,
+		Content:     a.ArticleContent,
+		ContentHTML: a.ContentHTML,
+		Status:      a.Status,
+		PublishAt:   a.PublishAt,
+		PublishedAt: a.PublishedAt,
+		NumViews:    a.NumViews,
+		Author:      Author{ID: a.Author.ID, Username: a.Author.Username},
+		Tags:        tags,
+		Reactions:   reactions,
+		CreatedAt:   a.CreatedAt,
+		UpdatedAt:   a.UpdatedAt,
+	}
+}
+
+func FromArticles(list []models.Article) []Article {
+	articles := make([]Article, 0, len(list))
+	for _, a := range list {
+		articles = append(articles, FromArticle(a))
+	}
+	return articles
+}
+
+func FromArticlePage(page service.ArticlePage) ArticleList {
+	return ArticleList{
+		Articles:   FromArticles(page.Articles),
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	}
+}
+
+type Comment struct {
+	ID        uint      `json:"id"`
+	ArticleID uint      `json:"article_id"`
+	ParentID  *uint     `json:"parent_id,omitempty"`
+	Author    Author    `json:"author"`
+	Body      string    `json:"body"`
+	Hidden    bool      `json:"hidden"`
+	Deleted   bool      `json:"deleted,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Replies   []Comment `json:"replies,omitempty"`
+}
+
+// CommentInput — тело запроса на создание или изменение комментария.
+type CommentInput struct {
+	Body     string `json:"body" form:"body"`
+	ParentID uint   `json:"parent_id" form:"parent_id"`
+}
+
+func FromComment(c models.Comment) Comment {
+	return Comment{
+		ID:        c.ID,
+		ArticleID: c.ArticleID,
+		ParentID:  c.ParentID,
+		Author:    Author{ID: c.Author.ID, Username: c.Author.Username},
+		Body:      c.Body,
+		Hidden:    c.Hidden,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+func FromCommentTree(nodes []*service.CommentNode) []Comment {
+	comments := make([]Comment, 0, len(nodes))
+	for _, node := range nodes {
+		comment := FromComment(node.Comment)
+		comment.Deleted = node.Deleted
+		comment.Body = node.Body
+		comment.Replies = FromCommentTree(node.Replies)
+		comments = append(comments, comment)
+	}
+	return comments
+}
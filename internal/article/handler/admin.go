@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"news/internal/article/service"
+	"news/pkg/database"
+
+	"github.com/labstack/echo/v4"
+)
+
+func tagAdminError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrTagNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrTagTooLong), errors.Is(err, service.ErrEmptyTag),
+		errors.Is(err, service.ErrTagMergeSelf), errors.Is(err, service.ErrAliasIsTag):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	log.Printf("tag admin error: %s", err)
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+}
+
+// MergeTags объединяет тег source с тегом target. Доступно только администраторам.
+func MergeTags(c echo.Context) error {
+	var req struct {
+		Source string `json:"source"`
+		Target string `json:"target"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат данных"})
+	}
+
+	tag, articleIDs, err := service.MergeTags(database.DB, req.Source, req.Target)
+	if err != nil {
+		return tagAdminError(c, err)
+	}
+	InvalidateArticlesCache(c.Request().Context(), articleIDs)
+	invalidateRelatedArticles(c.Request().Context(), articleIDs...)
+	invalidateFeeds(c.Request().Context())
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":          "теги объединены",
+		"tag":              tag.TagContent,
+		"updated_articles": len(articleIDs),
+	})
+}
+
+// AddTagAlias добавляет тегу псевдоним. Доступно только администраторам.
+func AddTagAlias(c echo.Context) error {
+	var req struct {
+		Alias string `json:"alias"`
+		Tag   string `json:"tag"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат данных"})
+	}
+
+	alias, err := service.AddTagAlias(database.DB, req.Alias, req.Tag)
+	if err != nil {
+		return tagAdminError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"alias": alias.Alias,
+		"tag":   alias.Tag.TagContent,
+	})
+}
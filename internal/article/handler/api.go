@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"news/internal/article/dto"
+	"news/internal/article/service"
+	"news/pkg/database"
+	"news/pkg/middleware"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const maxAPIPageSize = 50
+
+// apiPageSize читает параметр limit, ограничивая его сверху maxAPIPageSize.
+func apiPageSize(c echo.Context, defaultSize int) int {
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 {
+		return defaultSize
+	}
+	return min(limit, maxAPIPageSize)
+}
+
+func apiArticleID(c echo.Context) (uint64, error) {
+	return strconv.ParseUint(c.Param("id"), 10, 32)
+}
+
+// apiUserID возвращает ID пользователя из токена или 0 для анонимного запроса.
+func apiUserID(c echo.Context) uint {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return 0
+	}
+	return userID
+}
+
+func apiError(c echo.Context, status int, message string) error {
+	return c.JSON(status, dto.Error{Error: message})
+}
+
+func APIListArticles(c echo.Context) error {
+	sort := c.QueryParam("sort")
+	cursor, err := service.DecodeCursor(c.QueryParam("cursor"), service.ListCursorSorts(sort)...)
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, err.Error())
+	}
+	limit := apiPageSize(c, service.PopularArticlesLimit)
+	var page service.ArticlePage
+	switch sort {
+	case service.SortLatest, service.SortMostViewed:
+		page, err = service.GetArticlesWithDetails(database.DB, sort, cursor, limit)
+	case "", service.SortTrending:
+		page, err = service.GetTrendingArticles(c.Request().Context(), database.DB, redisClient, cursor, limit)
+	default:
+		return apiError(c, http.StatusBadRequest, "unknown sort: "+sort)
+	}
+	if err != nil {
+		log.Printf("error listing articles: %s", err)
+		return apiError(c, http.StatusInternalServerError, "internal server error")
+	}
+	c.Response().Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	return c.JSON(http.StatusOK, dto.FromArticlePage(page))
+}
+
+func APISearchArticles(c echo.Context) error {
+	query := strings.TrimSpace(c.QueryParam("q"))
+	if query == "" {
+		return apiError(c, http.StatusBadRequest, "query parameter q is required")
+	}
+	cursor, err := service.DecodeCursor(c.QueryParam("cursor"), service.SortRelevance)
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, err.Error())
+	}
+	page, err := service.SearchArticles(database.DB, query, cursor, apiPageSize(c, searchPageSize))
+	if err != nil {
+		log.Printf("error searching articles: %s", err)
+		return apiError(c, http.StatusInternalServerError, "internal server error")
+	}
+	c.Response().Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	return c.JSON(http.StatusOK, dto.FromArticlePage(page))
+}
+
+func APIGetArticle(c echo.Context) error {
+	articleID, err := apiArticleID(c)
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, "invalid article id")
+	}
+	article, err := loadArticle(c, articleID, apiUserID(c))
+	if errors.Is(err, service.ErrArticleNotFound) {
+		return apiError(c, http.StatusNotFound, "article not found")
+	}
+	if err != nil {
+		log.Printf("error getting article %d: %s", articleID, err)
+		return apiError(c, http.StatusInternalServerError, "internal server error")
+	}
+	out := dto.FromArticle(article)
+	related, err := service.GetRelatedArticles(c.Request().Context(), database.DB, redisClient, articleID)
+	if err != nil {
+		log.Printf("error getting related articles for article %d: %s", articleID, err)
+	}
+	out.Related = dto.FromArticles(related)
+	return c.JSON(http.StatusOK, out)
+}
+
+func APICreateArticle(c echo.Context) error {
+	var in dto.ArticleInput
+	if err := c.Bind(&in); err != nil {
+		return apiError(c, http.StatusBadRequest, "invalid request body")
+	}
+	title, content := strings.TrimSpace(in.Title), strings.TrimSpace(in.Content)
+	if title == "" || content == "" {
+		return apiError(c, http.StatusBadRequest, "title and content are required")
+	}
+	status, err := service.ResolveInitialStatus(strings.TrimSpace(in.Status), in.PublishAt)
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, err.Error())
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return apiError(c, http.StatusUnauthorized, "authentication required")
+	}
+
+	article, err := service.CreateArticle(database.DB, userID, title, content, in.TagString(), status, in.PublishAt)
+	if errors.Is(err, service.ErrTagTooLong) {
+		return apiError(c, http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		log.Printf("error creating article: %s", err)
+		return apiError(c, http.StatusInternalServerError, "internal server error")
+	}
+	invalidateFeeds(c.Request().Context())
+	return c.JSON(http.StatusCreated, dto.FromArticle(article))
+}
+
+func APIUpdateArticle(c echo.Context) error {
+	articleID, err := apiArticleID(c)
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, "invalid article id")
+	}
+	var in dto.ArticleInput
+	if err := c.Bind(&in); err != nil {
+		return apiError(c, http.StatusBadRequest, "invalid request body")
+	}
+	title, content := strings.TrimSpace(in.Title), strings.TrimSpace(in.Content)
+	if title == "" || content == "" {
+		return apiError(c, http.StatusBadRequest, "title and content are required")
+	}
+	// Статус меняется отдельным запросом с проверкой переходов, молча игнорировать его здесь нельзя
+	if in.Status != "" || in.PublishAt != nil {
+		return apiError(c, http.StatusBadRequest, "status and publish_at cannot be changed here, use POST /articles/{id}/status")
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return apiError(c, http.StatusUnauthorized, "authentication required")
+	}
+
+	article, err := service.UpdateArticle(database.DB, articleID, userID, title, content, in.TagString())
+	switch {
+	case errors.Is(err, service.ErrArticleNotFound):
+		return apiError(c, http.StatusNotFound, "article not found")
+	case errors.Is(err, service.ErrNotAuthor):
+		return apiError(c, http.StatusForbidden, "only the author can edit this article")
+	case errors.Is(err, service.ErrTagTooLong):
+		return apiError(c, http.StatusBadRequest, err.Error())
+	case err != nil:
+		log.Printf("error updating article %d: %s", articleID, err)
+		return apiError(c, http.StatusInternalServerError, "internal server error")
+	}
+	invalidateArticleCache(c.Request().Context(), c.Param("id"))
+	invalidateRelatedArticles(c.Request().Context(), article.ID)
+	invalidateFeeds(c.Request().Context())
+	return c.JSON(http.StatusOK, dto.FromArticle(article))
+}
+
+func APIDeleteArticle(c echo.Context) error {
+	articleID, err := apiArticleID(c)
+	if err != nil {
+		return apiError(c, http.StatusBadRequest, "invalid article id")
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return apiError(c, http.StatusUnauthorized, "authentication required")
+	}
+
+	err = service.DeleteArticle(database.DB, articleID, userID)
+	switch {
+	case errors.Is(err, service.ErrArticleNotFound):
+		return apiError(c, http.StatusNotFound, "article not found")
+	case errors.Is(err, service.ErrNotAuthor):
+		return apiError(c, http.StatusForbidden, "only the author can delete this article")
+	case err != nil:
+		log.Printf("error deleting article %d: %s", articleID, err)
+		return apiError(c, http.StatusInternalServerError, "internal server error")
+	}
+	invalidateArticleCache(c.Request().Context(), c.Param("id"))
+	invalidateFeeds(c.Request().Context())
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"news/internal/article/service"
+	"news/pkg/database"
+	"news/pkg/middleware"
+	"news/pkg/models"
+	"news/pkg/storage"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+var attachmentStorage storage.Storage
+
+// MaxUploadBytes — наибольший размер загружаемого изображения.
+var MaxUploadBytes int64 = 5 << 20
+
+func SetAttachmentStorage(s storage.Storage) {
+	attachmentStorage = s
+}
+
+func attachmentResponse(a models.Attachment) map[string]interface{} {
+	url := attachmentStorage.URL(a.StorageKey)
+	thumbnails := make(map[string]string, len(a.Thumbnails))
+	for width, key := range a.Thumbnails {
+		thumbnails[strconv.Itoa(width)] = attachmentStorage.URL(key)
+	}
+	return map[string]interface{}{
+		"id":           a.ID,
+		"article_id":   a.ArticleID,
+		"url":          url,
+		"thumbnails":   thumbnails,
+		"content_type": a.ContentType,
+		"size":         a.Size,
+		"width":        a.Width,
+		"height":       a.Height,
+		"markdown":     "![](" + url + ")",
+	}
+}
+
+// UploadAttachment принимает изображение в поле file формы multipart/form-data.
+// Необязательное поле article_id привязывает изображение к статье автора.
+func UploadAttachment(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil || userID == 0 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+	tooLarge := map[string]string{"error": "файл больше " + strconv.FormatInt(MaxUploadBytes>>10, 10) + " КБ"}
+	// Запас на заголовки multipart и остальные поля формы
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, MaxUploadBytes+64<<10)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "файл не передан"})
+	}
+	if fileHeader.Size > MaxUploadBytes {
+		return c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+	}
+	var articleID *uint
+	if value := c.FormValue("article_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID статьи"})
+		}
+		articleIDUint := uint(id)
+		articleID = &articleIDUint
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Printf("error opening uploaded file: %s", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "не удалось прочитать файл"})
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, MaxUploadBytes+1))
+	if err != nil {
+		log.Printf("error reading uploaded file: %s", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "не удалось прочитать файл"})
+	}
+	if int64(len(data)) > MaxUploadBytes {
+		return c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+	}
+
+	attachment, err := service.SaveAttachment(c.Request().Context(), database.DB, attachmentStorage, userID, articleID, data)
+	switch {
+	case errors.Is(err, service.ErrArticleNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "статья не найдена"})
+	case errors.Is(err, service.ErrNotAuthor):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrUnsupportedImage):
+		return c.JSON(http.StatusUnsupportedMediaType, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrImageTooLarge):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+	case err != nil:
+		log.Printf("error saving attachment of user %d: %s", userID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	return c.JSON(http.StatusCreated, attachmentResponse(attachment))
+}
+
+// DeleteAttachment удаляет загруженное изображение вместе с уменьшенными копиями.
+func DeleteAttachment(c echo.Context) error {
+	attachmentID, err := strconv.ParseUint(c.Param("attachment_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID вложения"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil || userID == 0 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+
+	err = service.DeleteAttachment(c.Request().Context(), database.DB, attachmentStorage, attachmentID, userID)
+	switch {
+	case errors.Is(err, service.ErrAttachmentNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrNotAuthor):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	case err != nil:
+		log.Printf("error deleting attachment %d: %s", attachmentID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ServeAttachment отдаёт загруженное изображение по ключу из пути. Изображения черновиков и
+// запланированных статей видят только автор и те, кто может видеть статью; остальным отвечает 404.
+func ServeAttachment(c echo.Context) error {
+	files, ok := attachmentStorage.(interface {
+		FilePath(key string) (string, error)
+	})
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "файл не найден"})
+	}
+	key := c.Param("*")
+	_, public, err := service.GetVisibleAttachment(database.DB, key, apiUserID(c))
+	switch {
+	case errors.Is(err, service.ErrAttachmentNotFound), errors.Is(err, service.ErrArticleNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "файл не найден"})
+	case err != nil:
+		log.Printf("error getting attachment %s: %s", key, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	path, err := files.FilePath(key)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "файл не найден"})
+	}
+	if public {
+		c.Response().Header().Set("Cache-Control", "public, max-age=86400")
+	} else {
+		c.Response().Header().Set("Cache-Control", "private, no-store")
+	}
+	return c.File(path)
+}
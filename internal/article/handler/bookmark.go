@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"news/internal/article/dto"
+	"news/internal/article/service"
+	"news/pkg/database"
+	"news/pkg/middleware"
+	"news/pkg/models"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+const bookmarksPageSize = 20
+
+// savedArticles возвращает, какие из статей сохранены пользователем, для отметки на странице списка.
+func savedArticles(userID uint, articles []models.Article) map[uint]bool {
+	ids := make([]uint, 0, len(articles))
+	for _, article := range articles {
+		ids = append(ids, article.ID)
+	}
+	saved, err := service.GetBookmarkedIDs(database.DB, userID, ids)
+	if err != nil {
+		log.Printf("error getting bookmarks of user %d: %s", userID, err)
+		return map[uint]bool{}
+	}
+	return saved
+}
+
+func bookmarkResponse(c echo.Context, articleID string, saved bool) error {
+	if isFormPost(c) {
+		referer := c.Request().Referer()
+		if referer == "" {
+			referer = "/article/" + articleID
+		}
+		return c.Redirect(http.StatusSeeOther, referer)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"article_id": articleID,
+		"saved":      saved,
+	})
+}
+
+func AddBookmark(c echo.Context) error {
+	articleID := c.Param("article_id")
+	articleIDUint, err := strconv.ParseUint(articleID, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID статьи"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil || userID == 0 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+
+	err = service.AddBookmark(database.DB, articleIDUint, userID)
+	if errors.Is(err, service.ErrArticleNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "статья не найдена"})
+	}
+	if err != nil {
+		log.Printf("error bookmarking article %d: %s", articleIDUint, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	return bookmarkResponse(c, articleID, true)
+}
+
+func RemoveBookmark(c echo.Context) error {
+	articleID := c.Param("article_id")
+	articleIDUint, err := strconv.ParseUint(articleID, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID статьи"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil || userID == 0 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+
+	if err := service.RemoveBookmark(database.DB, articleIDUint, userID); err != nil {
+		log.Printf("error removing bookmark on article %d: %s", articleIDUint, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	return bookmarkResponse(c, articleID, false)
+}
+
+// GetBookmarks показывает список для чтения текущего пользователя.
+func GetBookmarks(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil || userID == 0 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+	cursor, err := service.DecodeCursor(c.QueryParam("cursor"), service.SortBookmarked)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	page, err := service.GetBookmarks(database.DB, userID, cursor, apiPageSize(c, bookmarksPageSize))
+	if err != nil {
+		log.Printf("error getting bookmarks of user %d: %s", userID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	c.Response().Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if wantsJSON(c) {
+		return c.JSON(http.StatusOK, dto.FromArticlePage(page))
+	}
+	var currentUser models.User
+	database.DB.Select("username").First(&currentUser, userID)
+	saved := make(map[uint]bool, len(page.Articles))
+	for _, article := range page.Articles {
+		saved[article.ID] = true
+	}
+	return c.Render(http.StatusOK, "allArticle.html", map[string]interface{}{
+		"articles":        page.Articles,
+		"currentUsername": currentUser.Username,
+		"bookmarks":       true,
+		"saved":           saved,
+		"next_cursor":     page.NextCursor,
+		"total":           page.Total,
+	})
+}
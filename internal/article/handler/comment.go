@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"news/internal/article/dto"
+	"news/internal/article/service"
+	"news/pkg/database"
+	"news/pkg/middleware"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// isFormPost сообщает, что запрос отправлен HTML-формой: в ответ нужно перенаправление, а не JSON.
+func isFormPost(c echo.Context) bool {
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	return strings.HasPrefix(contentType, echo.MIMEApplicationForm) || strings.HasPrefix(contentType, echo.MIMEMultipartForm)
+}
+
+func commentRedirect(c echo.Context, articleID, commentID uint) error {
+	url := fmt.Sprintf("/article/%d", articleID)
+	if commentID != 0 {
+		url += fmt.Sprintf("#comment-%d", commentID)
+	}
+	return c.Redirect(http.StatusSeeOther, url)
+}
+
+func commentError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrArticleNotFound), errors.Is(err, service.ErrCommentNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrNotCommentAuthor), errors.Is(err, service.ErrNotArticleAuthor):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrEmptyComment), errors.Is(err, service.ErrCommentTooLong), errors.Is(err, service.ErrInvalidParent):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	log.Printf("comment error: %s", err)
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+}
+
+func GetComments(c echo.Context) error {
+	articleID, err := strconv.ParseUint(c.Param("article_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID статьи"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required: " + err.Error()})
+	}
+	article, err := service.GetVisibleArticle(database.DB, articleID, userID)
+	if err != nil {
+		return commentError(c, err)
+	}
+	tree, err := service.GetCommentTree(database.DB, article, userID)
+	if err != nil {
+		return commentError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"comments": dto.FromCommentTree(tree),
+	})
+}
+
+func AddComment(c echo.Context) error {
+	articleID, err := strconv.ParseUint(c.Param("article_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID статьи"})
+	}
+	var in dto.CommentInput
+	if err := c.Bind(&in); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат данных"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required: " + err.Error()})
+	}
+	var parentID *uint
+	if in.ParentID != 0 {
+		parentID = &in.ParentID
+	}
+
+	comment, err := service.CreateComment(database.DB, articleID, userID, parentID, in.Body)
+	if err != nil {
+		return commentError(c, err)
+	}
+	if isFormPost(c) {
+		return commentRedirect(c, comment.ArticleID, comment.ID)
+	}
+	return c.JSON(http.StatusCreated, dto.FromComment(comment))
+}
+
+func UpdateComment(c echo.Context) error {
+	commentID, err := strconv.ParseUint(c.Param("comment_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID комментария"})
+	}
+	var in dto.CommentInput
+	if err := c.Bind(&in); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат данных"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required: " + err.Error()})
+	}
+
+	comment, err := service.UpdateComment(database.DB, commentID, userID, in.Body)
+	if err != nil {
+		return commentError(c, err)
+	}
+	return c.JSON(http.StatusOK, dto.FromComment(comment))
+}
+
+func DeleteComment(c echo.Context) error {
+	commentID, err := strconv.ParseUint(c.Param("comment_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID комментария"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required: " + err.Error()})
+	}
+
+	articleID, err := service.DeleteComment(database.DB, commentID, userID)
+	if err != nil {
+		return commentError(c, err)
+	}
+	if c.Request().Method == http.MethodPost {
+		return commentRedirect(c, articleID, 0)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// HideComment и ShowComment позволяют автору статьи скрывать комментарии к ней и возвращать их.
+func HideComment(c echo.Context) error {
+	return setCommentHidden(c, true)
+}
+
+func ShowComment(c echo.Context) error {
+	return setCommentHidden(c, false)
+}
+
+func setCommentHidden(c echo.Context, hidden bool) error {
+	commentID, err := strconv.ParseUint(c.Param("comment_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID комментария"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required: " + err.Error()})
+	}
+
+	comment, err := service.SetCommentHidden(database.DB, commentID, userID, hidden)
+	if err != nil {
+		return commentError(c, err)
+	}
+	if isFormPost(c) {
+		return commentRedirect(c, comment.ArticleID, comment.ID)
+	}
+	return c.JSON(http.StatusOK, dto.FromComment(comment))
+}
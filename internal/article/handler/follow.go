@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"news/internal/article/dto"
+	"news/internal/article/service"
+	"news/pkg/database"
+	"news/pkg/middleware"
+	"news/pkg/models"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+const feedPageSize = 20
+
+func followResponse(c echo.Context, authorID uint64, following bool) error {
+	if isFormPost(c) {
+		referer := c.Request().Referer()
+		if referer == "" {
+			referer = "/feed"
+		}
+		return c.Redirect(http.StatusSeeOther, referer)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"author_id": authorID,
+		"following": following,
+	})
+}
+
+func FollowAuthor(c echo.Context) error {
+	authorID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID пользователя"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil || userID == 0 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+
+	err = service.FollowAuthor(database.DB, userID, uint(authorID))
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrSelfFollow):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case err != nil:
+		log.Printf("error following author %d: %s", authorID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	return followResponse(c, authorID, true)
+}
+
+func UnfollowAuthor(c echo.Context) error {
+	authorID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID пользователя"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil || userID == 0 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+
+	if err := service.UnfollowAuthor(database.DB, userID, uint(authorID)); err != nil {
+		log.Printf("error unfollowing author %d: %s", authorID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	return followResponse(c, authorID, false)
+}
+
+// GetFeed показывает ленту статей авторов и тегов, на которые подписан текущий пользователь.
+func GetFeed(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil || userID == 0 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+	cursor, err := service.DecodeCursor(c.QueryParam("cursor"), service.SortLatest)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	page, err := service.GetFeed(database.DB, userID, cursor, apiPageSize(c, feedPageSize))
+	if err != nil {
+		log.Printf("error getting feed of user %d: %s", userID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	c.Response().Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if wantsJSON(c) {
+		return c.JSON(http.StatusOK, dto.FromArticlePage(page))
+	}
+	var currentUser models.User
+	database.DB.Select("username").First(&currentUser, userID)
+	return c.Render(http.StatusOK, "allArticle.html", map[string]interface{}{
+		"articles":        page.Articles,
+		"currentUsername": currentUser.Username,
+		"feed":            true,
+		"saved":           savedArticles(userID, page.Articles),
+		"next_cursor":     page.NextCursor,
+		"total":           page.Total,
+	})
+}
+
+// GetAuthorArticles показывает опубликованные статьи автора.
+func GetAuthorArticles(c echo.Context) error {
+	authorID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID пользователя"})
+	}
+	author, err := service.GetAuthor(database.DB, uint(authorID))
+	if errors.Is(err, service.ErrUserNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		log.Printf("error getting author %d: %s", authorID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	cursor, err := service.DecodeCursor(c.QueryParam("cursor"), service.SortLatest)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	page, err := service.GetArticlesByAuthor(database.DB, author.ID, cursor, apiPageSize(c, feedPageSize))
+	if err != nil {
+		log.Printf("error getting articles of author %d: %s", author.ID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	c.Response().Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if wantsJSON(c) {
+		return c.JSON(http.StatusOK, dto.FromArticlePage(page))
+	}
+	userID := apiUserID(c)
+	following := false
+	if userID != 0 && userID != author.ID {
+		if following, err = service.IsFollowing(database.DB, userID, author.ID); err != nil {
+			log.Printf("error checking follow of author %d: %s", author.ID, err)
+		}
+	}
+	return c.Render(http.StatusOK, "allArticle.html", map[string]interface{}{
+		"articles":        page.Articles,
+		"currentUsername": currentUsername(userID),
+		"author":          author.Username,
+		"authorID":        author.ID,
+		"authorFollowed":  following,
+		"canFollow":       userID != 0 && userID != author.ID,
+		"saved":           savedArticles(userID, page.Articles),
+		"next_cursor":     page.NextCursor,
+		"total":           page.Total,
+	})
+}
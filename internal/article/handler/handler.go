@@ -3,9 +3,12 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"html/template"
 	"log"
 	"math/rand"
 	"net/http"
+	"news/internal/article/dto"
 	"news/internal/article/service"
 	"news/pkg/database"
 	"news/pkg/middleware"
@@ -20,13 +23,91 @@ import (
 
 var redisClient *redis.Client
 
-func AddArticle(c echo.Context) error {
-	type ArticleRequest struct {
-		Title   string `json:"article-title"`
-		Content string `json:"article-content"`
-		Tags    string `json:"tags"`
+const searchPageSize = 20
+
+// ViewDedupWindow — интервал, в течение которого повторные просмотры одного зрителя не засчитываются.
+var ViewDedupWindow = 30 * time.Minute
+
+func SetRedisClient(client *redis.Client) {
+	redisClient = client
+}
+
+type ArticleRequest struct {
+	Title   string `json:"article-title"`
+	Content string `json:"article-content"`
+	Tags    string `json:"tags"`
+	Status  string `json:"status"`
+	// PublishAt — время отложенной публикации в формате RFC 3339
+	PublishAt string `json:"publish_at"`
+}
+
+func articleCacheKey(articleID string) string {
+	return "article:" + articleID
+}
+
+// articleGenerationKey — счётчик сбросов кеша статьи. Копию, прочитанную из БД до сброса, записывать нельзя.
+func articleGenerationKey(articleID string) string {
+	return "article:" + articleID + ":gen"
+}
+
+// articleGenerationTTL с запасом больше времени чтения статьи из БД: пока счётчик жив, устаревшая копия не попадёт в кеш.
+const articleGenerationTTL = time.Hour
+
+// cacheArticleScript кладёт статью в кеш, только если с момента чтения поколения её кеш не сбрасывали.
+var cacheArticleScript = redis.NewScript(`
+if (redis.call('GET', KEYS[2]) or '0') ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'EX', ARGV[3])
+return 1
+`)
+
+// InvalidateArticlesCache сбрасывает закешированные копии статей, например после их отложенной публикации.
+func InvalidateArticlesCache(ctx context.Context, articleIDs []uint) {
+	for _, id := range articleIDs {
+		invalidateArticleCache(ctx, strconv.FormatUint(uint64(id), 10))
 	}
+}
+
+// countView засчитывает просмотр статьи и возвращает число просмотров, ещё не сброшенных в БД.
+func countView(c echo.Context, articleID uint64, userID uint) int {
+	viewer := "ip:" + c.RealIP()
+	if userID != 0 {
+		viewer = "user:" + strconv.FormatUint(uint64(userID), 10)
+	}
+	ctx := c.Request().Context()
+	if _, err := service.RecordView(ctx, redisClient, articleID, viewer, ViewDedupWindow); err != nil {
+		log.Printf("failed to record view for article %d: %v", articleID, err)
+	}
+	return service.PendingViews(ctx, redisClient, articleID)
+}
+
+// invalidateRelatedArticles сбрасывает подборки похожих статей после изменения тегов или текста статей.
+func invalidateRelatedArticles(ctx context.Context, articleIDs ...uint) {
+	if redisClient == nil {
+		return
+	}
+	if err := service.InvalidateRelatedArticles(ctx, redisClient, articleIDs); err != nil {
+		log.Printf("failed to invalidate related articles cache: %v", err)
+	}
+}
+
+func invalidateArticleCache(ctx context.Context, articleID string) {
+	if redisClient == nil {
+		return
+	}
+	_, err := redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, articleGenerationKey(articleID))
+		pipe.Expire(ctx, articleGenerationKey(articleID), articleGenerationTTL)
+		pipe.Del(ctx, articleCacheKey(articleID))
+		return nil
+	})
+	if err != nil {
+		log.Printf("failed to invalidate cache for article %s: %v", articleID, err)
+	}
+}
 
+func AddArticle(c echo.Context) error {
 	var req ArticleRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат данных"})
@@ -41,6 +122,14 @@ func AddArticle(c echo.Context) error {
 			"error": "Заголовок и содержание статьи обязательны",
 		})
 	}
+	publishAt, err := parsePublishAt(req.PublishAt)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат времени публикации"})
+	}
+	status, err := service.ResolveInitialStatus(strings.TrimSpace(req.Status), publishAt)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 	userID, err := middleware.GetUserIDFromToken(c)
 	if err != nil {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
@@ -48,82 +137,15 @@ func AddArticle(c echo.Context) error {
 		})
 	}
 
-	tx := database.DB.Begin()
-	if tx.Error != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка начала транзакции"})
-	}
-
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	article := models.Article{
-		AuthorID:       userID,
-		ArticleTitle:   title,
-		ArticleContent: content,
-	}
-	if err := tx.Create(&article).Error; err != nil {
-		tx.Rollback()
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "не удалось создать статью:" + err.Error()})
-	}
-	if inputTags != "" {
-		tagNames := strings.Split(inputTags, ",")
-		uniqueTags := make(map[string]bool)
-		var tagsToProcess []string
-
-		for _, tagName := range tagNames {
-			tagName = strings.TrimSpace(tagName)
-			if tagName != "" && !uniqueTags[tagName] {
-				uniqueTags[tagName] = true
-				tagsToProcess = append(tagsToProcess, tagName)
-			}
-		}
-		if len(tagsToProcess) > 0 {
-			var existTags []models.Tag
-			if err := tx.Where("tag_content IN (?)", tagsToProcess).Find(&existTags).Error; err != nil {
-				tx.Rollback()
-				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка при поиске тегов"})
-			}
-			existingTagMap := make(map[string]models.Tag)
-			for _, tag := range existTags {
-				existingTagMap[tag.TagContent] = tag
-			}
-			var newTags []models.Tag
-			for _, tagname := range tagsToProcess {
-				if _, exists := existingTagMap[tagname]; !exists {
-					newTags = append(newTags, models.Tag{TagContent: tagname})
-				}
-			}
-			if len(newTags) > 0 {
-				if err := tx.Create(&newTags).Error; err != nil {
-					return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка при создании тегов"})
-				}
-				for _, tag := range newTags {
-					existingTagMap[tag.TagContent] = tag
-				}
-			}
-
-			var articleTags []models.Tag
-			for _, tagname := range tagsToProcess {
-				articleTags = append(articleTags, existingTagMap[tagname])
-			}
-			if err := tx.Model(&article).Association("Tags").Append(articleTags); err != nil {
-				tx.Rollback()
-				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка при связывании тега со статьей"})
-			}
-		}
-
+	article, err := service.CreateArticle(database.DB, userID, title, content, inputTags, status, publishAt)
+	if errors.Is(err, service.ErrTagTooLong) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка при коммите транзакции"})
-	}
-
-	if err := database.DB.Preload("Tags").First(&article, article.ID).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка при загрузке статьи"})
+	if err != nil {
+		log.Printf("error creating article: %s", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "не удалось создать статью"})
 	}
+	invalidateFeeds(c.Request().Context())
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"message": "статья успешно создана",
@@ -131,12 +153,42 @@ func AddArticle(c echo.Context) error {
 	})
 }
 
+// parsePublishAt разбирает время отложенной публикации в формате RFC 3339. Пустая строка — публикация без отложенного времени.
+func parsePublishAt(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
 func AllArticle(c echo.Context) error {
-	articles, err := service.GetArticlesWithDetails(database.DB)
+	sort := c.QueryParam("sort")
+	cursor, err := service.DecodeCursor(c.QueryParam("cursor"), service.ListCursorSorts(sort)...)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	var page service.ArticlePage
+	switch sort {
+	case service.SortLatest, service.SortMostViewed:
+		page, err = service.GetArticlesWithDetails(database.DB, sort, cursor, service.PopularArticlesLimit)
+	default:
+		sort = service.SortTrending
+		page, err = service.GetTrendingArticles(c.Request().Context(), database.DB, redisClient, cursor, service.PopularArticlesLimit)
+	}
 	if err != nil {
 		log.Printf("error get articles from DB: %s", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "error in get articles from DB"})
 	}
+	articles := page.Articles
+	c.Response().Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if wantsJSON(c) {
+		return c.JSON(http.StatusOK, dto.FromArticlePage(page))
+	}
 	userID, err := middleware.GetUserIDFromToken(c)
 	if err != nil {
 		log.Printf("error get userID from token: %s", err)
@@ -151,45 +203,368 @@ func AllArticle(c echo.Context) error {
 	return c.Render(http.StatusOK, "allArticle.html", map[string]interface{}{
 		"articles":        articles,
 		"currentUsername": currentUsername,
+		"saved":           savedArticles(userID, articles),
+		"sort":            sort,
+		"next_cursor":     page.NextCursor,
+		"total":           page.Total,
 	})
 }
 
+// resolveArticleParam разбирает параметр article_id, в котором может быть ID или slug статьи.
+// Если статью нужно открыть по другому адресу (числовой ID или прежний slug), возвращает этот адрес.
+func resolveArticleParam(c echo.Context, userID uint) (uint64, string, error) {
+	param := c.Param("article_id")
+	if articleID, err := strconv.ParseUint(param, 10, 32); err == nil {
+		if wantsJSON(c) {
+			return articleID, "", nil
+		}
+		ref, err := service.GetArticleRef(database.DB, articleID)
+		if err != nil {
+			return 0, "", err
+		}
+		if !service.CanViewArticle(ref, userID) {
+			return 0, "", service.ErrArticleNotFound
+		}
+		if ref.Slug == "" {
+			return articleID, "", nil
+		}
+		return articleID, ref.URLPath(), nil
+	}
+	articleID, slug, err := service.ResolveArticleSlug(database.DB, param)
+	if err != nil {
+		return 0, "", err
+	}
+	if slug != param {
+		// Новый slug повторяет новый заголовок, поэтому по старой ссылке его узнаёт только тот, кто может видеть статью
+		ref, err := service.GetArticleRef(database.DB, uint64(articleID))
+		if err != nil {
+			return 0, "", err
+		}
+		if !service.CanViewArticle(ref, userID) {
+			return 0, "", service.ErrArticleNotFound
+		}
+		return uint64(articleID), "/article/" + slug, nil
+	}
+	return uint64(articleID), "", nil
+}
+
+// GetArticle показывает статью. Страница публичная: анонимный читатель видит опубликованную статью,
+// черновики доступны только автору.
 func GetArticle(c echo.Context) error {
-	articleID := c.Param("article_id")
+	userID := apiUserID(c)
+	articleIDUint, canonical, err := resolveArticleParam(c, userID)
+	if errors.Is(err, service.ErrArticleNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "статья не найдена"})
+	}
+	if err != nil {
+		log.Printf("error resolving article %q: %s", c.Param("article_id"), err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	if canonical != "" {
+		if query := c.Request().URL.RawQuery; query != "" {
+			canonical += "?" + query
+		}
+		return c.Redirect(http.StatusMovedPermanently, canonical)
+	}
+	article, err := loadArticle(c, articleIDUint, userID)
+	if errors.Is(err, service.ErrArticleNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "статья не найдена"})
+	}
+	if err != nil {
+		log.Printf("error in getting article by ID: %s", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	related, err := service.GetRelatedArticles(c.Request().Context(), database.DB, redisClient, articleIDUint)
+	if err != nil {
+		log.Printf("error getting related articles for article %d: %s", articleIDUint, err)
+	}
+	if wantsJSON(c) {
+		out := dto.FromArticle(article)
+		out.Related = dto.FromArticles(related)
+		return c.JSON(http.StatusOK, out)
+	}
+	comments, err := service.GetCommentTree(database.DB, article, userID)
+	if err != nil {
+		log.Printf("error getting comments for article %d: %s", articleIDUint, err)
+	}
+	userReactions, err := service.GetUserReactions(database.DB, articleIDUint, userID)
+	if err != nil {
+		log.Printf("error getting reactions for article %d: %s", articleIDUint, err)
+	}
+	saved, err := service.IsBookmarked(database.DB, articleIDUint, userID)
+	if err != nil {
+		log.Printf("error getting bookmark for article %d: %s", articleIDUint, err)
+	}
+	following, err := service.IsFollowing(database.DB, userID, article.AuthorID)
+	if err != nil {
+		log.Printf("error getting follow status for author %d: %s", article.AuthorID, err)
+	}
+	return c.Render(http.StatusOK, "article.html", articleView{
+		Article:         article,
+		Comments:        comments,
+		Related:         related,
+		ReactionButtons: reactionButtons(article.Reactions, userReactions),
+		Saved:           saved,
+		FollowingAuthor: following,
+		CurrentUserID:   userID,
+	})
+}
+
+// articleView — данные шаблона article.html: поля статьи доступны в шаблоне напрямую.
+type articleView struct {
+	models.Article
+	Comments        []*service.CommentNode
+	Related         []models.Article
+	ReactionButtons []reactionButton
+	Saved           bool // статья в списке для чтения текущего пользователя
+	FollowingAuthor bool // текущий пользователь подписан на автора статьи
+	CurrentUserID   uint
+}
+
+// Content возвращает очищенный HTML статьи. Для статьи без HTML текст выводится как есть, с экранированием.
+func (v articleView) Content() template.HTML {
+	if v.ContentHTML == "" {
+		return template.HTML(template.HTMLEscapeString(v.ArticleContent))
+	}
+	return template.HTML(v.ContentHTML)
+}
+
+// loadArticle возвращает статью из кеша или из БД, проверяет право пользователя на её просмотр и засчитывает просмотр.
+func loadArticle(c echo.Context, articleID uint64, userID uint) (models.Article, error) {
+	id := strconv.FormatUint(articleID, 10)
+	ctx := c.Request().Context()
+	var article models.Article
+	cachedData, err := redisClient.Get(ctx, articleCacheKey(id)).Result()
+	if err != nil || json.Unmarshal([]byte(cachedData), &article) != nil {
+		// Поколение читается до БД: если статью изменят во время чтения, копия в кеш не попадёт
+		generation, genErr := redisClient.Get(ctx, articleGenerationKey(id)).Result()
+		if errors.Is(genErr, redis.Nil) {
+			generation, genErr = "0", nil
+		}
+		article, err = service.GetArticleByIDFromDB(database.DB, articleID)
+		if err != nil {
+			return models.Article{}, err
+		}
+		if genErr == nil {
+			cacheArticle(ctx, id, generation, article)
+		}
+	}
+	if !service.CanViewArticle(article, userID) {
+		return models.Article{}, service.ErrArticleNotFound
+	}
+	article.NumViews += countView(c, articleID, userID)
+	return article, nil
+}
+
+// cacheArticle кладёт статью в кеш, если её поколение всё ещё generation.
+func cacheArticle(ctx context.Context, articleID, generation string, article models.Article) {
+	serialized, err := json.Marshal(article)
+	if err != nil {
+		log.Printf("failed to marshal article for cache: %v", err)
+		return
+	}
+	// Устанавливаем TTL 5 минут с небольшим случайным отклонением (jitter) для защиты от одновременного протухания многих ключей :cite[1]
+	ttl := 5*time.Minute + time.Duration(rand.Intn(30))*time.Second
+	keys := []string{articleCacheKey(articleID), articleGenerationKey(articleID)}
+	if err := cacheArticleScript.Run(ctx, redisClient, keys, generation, serialized, int(ttl.Seconds())).Err(); err != nil {
+		log.Printf("failed to set cache: %v", err)
+	}
+}
+
+// wantsJSON сообщает, что клиент явно запросил JSON вместо HTML-страницы.
+func wantsJSON(c echo.Context) bool {
+	accept := c.Request().Header.Get(echo.HeaderAccept)
+	return strings.Contains(accept, echo.MIMEApplicationJSON) && !strings.Contains(accept, echo.MIMETextHTML)
+}
+
+func UpdateArticle(c echo.Context) error {
+	articleID := c.Param("id")
 	articleIDUint, err := strconv.ParseUint(articleID, 10, 32)
 	if err != nil {
 		log.Printf("error parse articleID -> uint: %s", err)
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID статьи"})
 	}
-	cacheKey := "article:" + articleID
-	cachedData, err := redisClient.Get(c.Request().Context(), cacheKey).Result()
-	if err == nil {
-		// Кеш найден, возвращаем данные
-		var article models.Article
-		json.Unmarshal([]byte(cachedData), &article)
-		return c.JSON(http.StatusOK, article)
+	var req ArticleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат данных"})
 	}
+	title := strings.TrimSpace(req.Title)
+	content := strings.TrimSpace(req.Content)
+	if title == "" || content == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Заголовок и содержание статьи обязательны",
+		})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Authentication required: " + err.Error(),
+		})
+	}
+
+	article, err := service.UpdateArticle(database.DB, articleIDUint, userID, title, content, req.Tags)
+	switch {
+	case errors.Is(err, service.ErrArticleNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrNotAuthor):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrTagTooLong):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case err != nil:
+		log.Printf("error updating article %d: %s", articleIDUint, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка при обновлении статьи"})
+	}
+	invalidateArticleCache(c.Request().Context(), articleID)
+	invalidateRelatedArticles(c.Request().Context(), article.ID)
+	invalidateFeeds(c.Request().Context())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "статья успешно обновлена",
+		"article": article,
+	})
+}
 
-	article, err := service.GetArticleByIDFromDB(database.DB, articleIDUint)
+func ChangeArticleStatus(c echo.Context) error {
+	articleID := c.Param("id")
+	articleIDUint, err := strconv.ParseUint(articleID, 10, 32)
 	if err != nil {
-		log.Printf("error in getting article by ID: %s", err)
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "ошибка на стороне сервера"})
+		log.Printf("error parse articleID -> uint: %s", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID статьи"})
+	}
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат данных"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Authentication required: " + err.Error(),
+		})
 	}
 
-	go func(art models.Article) {
-		serialized, err := json.Marshal(art)
-		if err != nil {
-			log.Printf("failed to marshal article for cache: %v", err)
-			return
+	article, err := service.ChangeArticleStatus(database.DB, articleIDUint, userID, strings.TrimSpace(req.Status))
+	switch {
+	case errors.Is(err, service.ErrArticleNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrNotAuthor):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidStatus), errors.Is(err, service.ErrInvalidStatusTransition):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+	case err != nil:
+		log.Printf("error changing status of article %d: %s", articleIDUint, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка при изменении статуса статьи"})
+	}
+	invalidateArticleCache(c.Request().Context(), articleID)
+	invalidateFeeds(c.Request().Context())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "статус статьи изменён",
+		"article": article,
+	})
+}
+
+func GetArticleRevisions(c echo.Context) error {
+	articleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		log.Printf("error parse articleID -> uint: %s", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID статьи"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Authentication required: " + err.Error(),
+		})
+	}
+	if _, err := service.GetVisibleArticle(database.DB, articleID, userID); err != nil {
+		if errors.Is(err, service.ErrArticleNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "статья не найдена"})
 		}
-		// Устанавливаем TTL 5 минут с небольшим случайным отклонением (jitter) для защиты от одновременного протухания многих ключей :cite[1]
-		ttl := 5*time.Minute + time.Duration(rand.Intn(30))*time.Second
-		if err := redisClient.Set(context.Background(), cacheKey, serialized, ttl).Err(); err != nil {
-			log.Printf("failed to set cache: %v", err)
+		log.Printf("error getting article %d: %s", articleID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	revisions, err := service.GetRevisions(database.DB, articleID)
+	if err != nil {
+		log.Printf("error getting revisions for article %d: %s", articleID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"revisions": revisions,
+	})
+}
+
+func DiffArticleRevisions(c echo.Context) error {
+	articleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		log.Printf("error parse articleID -> uint: %s", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID статьи"})
+	}
+	fromID, errFrom := strconv.ParseUint(c.QueryParam("from"), 10, 32)
+	toID, errTo := strconv.ParseUint(c.QueryParam("to"), 10, 32)
+	if errFrom != nil || errTo != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Параметры from и to должны содержать ID ревизий"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Authentication required: " + err.Error(),
+		})
+	}
+	if _, err := service.GetVisibleArticle(database.DB, articleID, userID); err != nil {
+		if errors.Is(err, service.ErrArticleNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "статья не найдена"})
 		}
-	}(article)
+		log.Printf("error getting article %d: %s", articleID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	diff, err := service.DiffRevisions(database.DB, articleID, fromID, toID)
+	if errors.Is(err, service.ErrRevisionNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		log.Printf("error diffing revisions of article %d: %s", articleID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	return c.JSON(http.StatusOK, diff)
+}
 
-	return c.Render(http.StatusOK, "article.html", article)
+func RestoreArticleRevision(c echo.Context) error {
+	articleID := c.Param("id")
+	articleIDUint, err := strconv.ParseUint(articleID, 10, 32)
+	if err != nil {
+		log.Printf("error parse articleID -> uint: %s", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID статьи"})
+	}
+	revisionID, err := strconv.ParseUint(c.Param("revision_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID ревизии"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Authentication required: " + err.Error(),
+		})
+	}
+
+	article, err := service.RestoreRevision(database.DB, articleIDUint, revisionID, userID)
+	switch {
+	case errors.Is(err, service.ErrArticleNotFound), errors.Is(err, service.ErrRevisionNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrNotAuthor):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	case err != nil:
+		log.Printf("error restoring revision %d of article %d: %s", revisionID, articleIDUint, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка при восстановлении ревизии"})
+	}
+	invalidateArticleCache(c.Request().Context(), articleID)
+	invalidateRelatedArticles(c.Request().Context(), article.ID)
+	invalidateFeeds(c.Request().Context())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "ревизия восстановлена",
+		"article": article,
+	})
 }
 
 func DeleteArticle(c echo.Context) error {
@@ -205,18 +580,17 @@ func DeleteArticle(c echo.Context) error {
 		log.Printf("error getting userID from token %s", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
 	}
-	var article models.Article
-	result := database.DB.Preload("Author").First(&article, articleID)
-	if result.Error != nil {
+	err = service.DeleteArticle(database.DB, articleUint, userID)
+	switch {
+	case errors.Is(err, service.ErrArticleNotFound):
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "статья не найдена"})
-	}
-	if article.AuthorID != userID {
+	case errors.Is(err, service.ErrNotAuthor):
 		return c.JSON(http.StatusForbidden, map[string]string{"error": "у вас нет прав для удаления данной записи"})
-	}
-	err = service.DeleteArticleByID(database.DB, articleUint)
-	if err != nil {
+	case err != nil:
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка при удалении статьи"})
 	}
+	invalidateArticleCache(c.Request().Context(), articleID)
+	invalidateFeeds(c.Request().Context())
 	return c.Redirect(http.StatusFound, referer)
 }
 
@@ -228,42 +602,23 @@ func SearchArticles(c echo.Context) error {
 		})
 	}
 
-	page, _ := strconv.Atoi(c.QueryParam("page"))
-	if page < 1 {
-		page = 1
+	cursor, err := service.DecodeCursor(c.QueryParam("cursor"), service.SortRelevance)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
-	limit := 20
-	offset := (page - 1) * limit
-
-	var articles []models.Article
 
 	// Безопасный поиск с использованием полнотекстовых возможностей PostgreSQL
-	query := database.DB.Preload("Author").Preload("Tags").
-		Where("articles.deleted_at IS NULL")
-
-	if searchQuery != "" {
-		// Используем phraseto_tsquery для поиска точной фразы
-		query = query.Where(`
-            search_vector @@ phraseto_tsquery('russian', ?) OR
-            articles.id IN (
-                SELECT at.article_id FROM article_tags at
-                JOIN tags t ON t.id = at.tag_id
-                WHERE to_tsvector('russian', t.tag_content) @@ phraseto_tsquery('russian', ?)
-            )
-        `, searchQuery, searchQuery).
-			Select("*, ts_rank(search_vector, phraseto_tsquery('russian', ?)) as rank", searchQuery).
-			Order("rank DESC, created_at DESC")
-	}
-
-	// Получаем результаты с пагинацией
-	result := query.Offset(offset).Limit(limit).Find(&articles)
-
-	if result.Error != nil {
-		log.Printf("Ошибка при поиске статей: %v", result.Error)
+	page, err := service.SearchArticles(database.DB, searchQuery, cursor, searchPageSize)
+	if err != nil {
+		log.Printf("Ошибка при поиске статей: %v", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Ошибка при поиске статей",
 		})
 	}
+	c.Response().Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if wantsJSON(c) {
+		return c.JSON(http.StatusOK, dto.FromArticlePage(page))
+	}
 	userID, err := middleware.GetUserIDFromToken(c)
 	if err != nil {
 		log.Printf("error getting userID from token: %s", err)
@@ -279,7 +634,11 @@ func SearchArticles(c echo.Context) error {
 	currentUsername := currentUser.Username
 
 	return c.Render(http.StatusOK, "allArticle.html", map[string]interface{}{
-		"articles":        articles,
+		"articles":        page.Articles,
 		"currentUsername": currentUsername,
+		"saved":           savedArticles(userID, page.Articles),
+		"searchQuery":     searchQuery,
+		"next_cursor":     page.NextCursor,
+		"total":           page.Total,
 	})
 }
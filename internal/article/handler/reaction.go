@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"news/internal/article/service"
+	"news/pkg/database"
+	"news/pkg/middleware"
+	"news/pkg/models"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// reactionEmoji задаёт подписи кнопок реакций на странице статьи.
+var reactionEmoji = map[string]string{
+	models.ReactionLike:    "👍",
+	models.ReactionDislike: "👎",
+	models.ReactionLove:    "❤️",
+	models.ReactionLaugh:   "😂",
+	models.ReactionWow:     "😮",
+	models.ReactionSad:     "😢",
+}
+
+// reactionButton — кнопка реакции в шаблоне article.html.
+type reactionButton struct {
+	Kind   string
+	Emoji  string
+	Count  int64
+	Active bool // реакция уже поставлена текущим пользователем
+}
+
+func reactionButtons(counts map[string]int64, userReactions map[string]bool) []reactionButton {
+	buttons := make([]reactionButton, 0, len(service.ReactionKinds))
+	for _, kind := range service.ReactionKinds {
+		buttons = append(buttons, reactionButton{
+			Kind:   kind,
+			Emoji:  reactionEmoji[kind],
+			Count:  counts[kind],
+			Active: userReactions[kind],
+		})
+	}
+	return buttons
+}
+
+// ToggleReaction ставит реакцию на статью или снимает её, если она уже стоит.
+func ToggleReaction(c echo.Context) error {
+	articleID := c.Param("article_id")
+	articleIDUint, err := strconv.ParseUint(articleID, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат ID статьи"})
+	}
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil || userID == 0 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+
+	reacted, counts, err := service.ToggleReaction(database.DB, articleIDUint, userID, c.Param("kind"))
+	switch {
+	case errors.Is(err, service.ErrArticleNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "статья не найдена"})
+	case errors.Is(err, service.ErrInvalidReaction):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case err != nil:
+		log.Printf("error toggling reaction on article %d: %s", articleIDUint, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	// Счётчики реакций хранятся в закешированной копии статьи
+	invalidateArticleCache(c.Request().Context(), articleID)
+
+	if isFormPost(c) {
+		return c.Redirect(http.StatusSeeOther, "/article/"+articleID+"#reactions")
+	}
+	if counts == nil {
+		counts = map[string]int64{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"reacted":   reacted,
+		"reactions": counts,
+	})
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"news/internal/article/service"
+	"news/pkg/database"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetSitemapIndex отдаёт sitemap.xml — индекс страниц карты сайта.
+func GetSitemapIndex(c echo.Context) error {
+	pages, err := service.GetSitemapPages(database.DB)
+	if err != nil {
+		log.Printf("error building sitemap index: %s", err)
+		return c.String(http.StatusInternalServerError, "ошибка на стороне сервера")
+	}
+	body, err := service.RenderSitemapIndex(siteURL, pages)
+	if err != nil {
+		log.Printf("error rendering sitemap index: %s", err)
+		return c.String(http.StatusInternalServerError, "ошибка на стороне сервера")
+	}
+	return c.Blob(http.StatusOK, echo.MIMEApplicationXMLCharsetUTF8, body)
+}
+
+// GetSitemapPage отдаёт страницу карты сайта /sitemaps/<n>.xml с адресами статей.
+func GetSitemapPage(c echo.Context) error {
+	page, err := strconv.Atoi(strings.TrimSuffix(c.Param("page"), ".xml"))
+	if err != nil || page < 1 {
+		return c.String(http.StatusNotFound, "страница карты сайта не найдена")
+	}
+	articles, err := service.GetSitemapArticles(database.DB, page)
+	if err != nil {
+		log.Printf("error building sitemap page %d: %s", page, err)
+		return c.String(http.StatusInternalServerError, "ошибка на стороне сервера")
+	}
+	if len(articles) == 0 {
+		return c.String(http.StatusNotFound, "страница карты сайта не найдена")
+	}
+	body, err := service.RenderSitemap(siteURL, articles)
+	if err != nil {
+		log.Printf("error rendering sitemap page %d: %s", page, err)
+		return c.String(http.StatusInternalServerError, "ошибка на стороне сервера")
+	}
+	return c.Blob(http.StatusOK, echo.MIMEApplicationXMLCharsetUTF8, body)
+}
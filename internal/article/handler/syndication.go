@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"news/internal/article/service"
+	"news/pkg/database"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// feedMaxAge — сколько секунд читатели лент могут не перезапрашивать ленту.
+const feedMaxAge = 300
+
+// siteURL — публичный адрес сайта для абсолютных ссылок в лентах и карте сайта. Берётся только
+// из настроек: адрес из запроса попал бы в закешированную ленту для всех читателей.
+var siteURL string
+
+func SetSiteURL(url string) {
+	siteURL = url
+}
+
+func feedFormat(c echo.Context) string {
+	if strings.HasSuffix(c.Path(), ".atom") {
+		return service.FeedFormatAtom
+	}
+	return service.FeedFormatRSS
+}
+
+// notModified проверяет условные заголовки запроса. If-None-Match приоритетнее If-Modified-Since.
+func notModified(r *http.Request, feed service.RenderedFeed) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, etag := range strings.Split(match, ",") {
+			etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+			if etag == feed.ETag || etag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !feed.LastModified.After(since)
+}
+
+func serveFeed(c echo.Context, scope service.FeedScope, meta service.FeedMeta) error {
+	format := feedFormat(c)
+	meta.BaseURL = siteURL
+	meta.SelfPath = c.Request().URL.Path
+	feed, err := service.GetSyndicationFeed(c.Request().Context(), database.DB, redisClient, format, scope, meta)
+	if err != nil {
+		log.Printf("error building %s feed: %s", format, err)
+		return c.String(http.StatusInternalServerError, "ошибка на стороне сервера")
+	}
+
+	header := c.Response().Header()
+	header.Set("ETag", feed.ETag)
+	header.Set(echo.HeaderLastModified, feed.LastModified.UTC().Format(http.TimeFormat))
+	header.Set("Cache-Control", "public, max-age="+strconv.Itoa(feedMaxAge))
+	if notModified(c.Request(), feed) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	contentType := "application/rss+xml; charset=utf-8"
+	if format == service.FeedFormatAtom {
+		contentType = "application/atom+xml; charset=utf-8"
+	}
+	return c.Blob(http.StatusOK, contentType, feed.Body)
+}
+
+// GetSiteFeed отдаёт RSS- или Atom-ленту последних статей.
+func GetSiteFeed(c echo.Context) error {
+	return serveFeed(c, service.FeedScope{}, service.FeedMeta{
+		Title:       "keprNews",
+		Description: "Последние статьи",
+		Path:        "/popular-news",
+	})
+}
+
+// GetTagFeed отдаёт ленту последних статей с тегом.
+func GetTagFeed(c echo.Context) error {
+	tag, err := service.GetTagByName(database.DB, tagParam(c))
+	if errors.Is(err, service.ErrTagNotFound) {
+		return c.String(http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		log.Printf("error getting tag %q: %s", tagParam(c), err)
+		return c.String(http.StatusInternalServerError, "ошибка на стороне сервера")
+	}
+	return serveFeed(c, service.FeedScope{Tag: &tag}, service.FeedMeta{
+		Title:       "keprNews: #" + tag.TagContent,
+		Description: "Последние статьи с тегом " + tag.TagContent,
+		Path:        "/tags/" + url.PathEscape(tag.TagContent),
+	})
+}
+
+// GetAuthorFeed отдаёт ленту последних статей автора.
+func GetAuthorFeed(c echo.Context) error {
+	authorID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Неверный формат ID пользователя")
+	}
+	author, err := service.GetAuthor(database.DB, uint(authorID))
+	if errors.Is(err, service.ErrUserNotFound) {
+		return c.String(http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		log.Printf("error getting author %d: %s", authorID, err)
+		return c.String(http.StatusInternalServerError, "ошибка на стороне сервера")
+	}
+	return serveFeed(c, service.FeedScope{AuthorID: author.ID}, service.FeedMeta{
+		Title:       "keprNews: " + author.Username,
+		Description: "Последние статьи автора " + author.Username,
+		Path:        "/users/" + strconv.FormatUint(uint64(author.ID), 10),
+	})
+}
+
+// invalidateFeeds сбрасывает закешированные ленты после изменения опубликованных статей.
+func invalidateFeeds(ctx context.Context) {
+	if redisClient == nil {
+		return
+	}
+	if err := service.InvalidateSyndicationFeeds(ctx, redisClient); err != nil {
+		log.Printf("failed to invalidate feeds cache: %v", err)
+	}
+}
+
+// OnArticlesPublished вызывается после отложенной публикации статей: сбрасывает их кеш и ленты.
+func OnArticlesPublished(ctx context.Context, articleIDs []uint) {
+	InvalidateArticlesCache(ctx, articleIDs)
+	if len(articleIDs) > 0 {
+		invalidateFeeds(ctx)
+	}
+}
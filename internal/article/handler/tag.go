@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"news/internal/article/dto"
+	"news/internal/article/service"
+	"news/pkg/database"
+	"news/pkg/middleware"
+	"news/pkg/models"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const tagPageSize = 20
+
+// tagParam возвращает имя тега из пути. Echo отдаёт параметр в закодированном виде,
+// если путь содержит экранированные символы.
+func tagParam(c echo.Context) string {
+	name, err := url.PathUnescape(c.Param("name"))
+	if err != nil {
+		name = c.Param("name")
+	}
+	return strings.TrimSpace(name)
+}
+
+func currentUsername(userID uint) string {
+	if userID == 0 {
+		return ""
+	}
+	var user models.User
+	database.DB.Select("username").First(&user, userID)
+	return user.Username
+}
+
+// GetTags показывает все теги с числом опубликованных статей.
+func GetTags(c echo.Context) error {
+	tags, err := service.GetTagSummaries(database.DB)
+	if err != nil {
+		log.Printf("error getting tags: %s", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	if wantsJSON(c) {
+		return c.JSON(http.StatusOK, map[string]interface{}{"tags": tags})
+	}
+	userID := apiUserID(c)
+	followed, err := service.GetFollowedTagIDs(database.DB, userID)
+	if err != nil {
+		log.Printf("error getting followed tags of user %d: %s", userID, err)
+	}
+	return c.Render(http.StatusOK, "tags.html", map[string]interface{}{
+		"tags":            tags,
+		"followed":        followed,
+		"currentUsername": currentUsername(userID),
+	})
+}
+
+// GetTagArticles показывает опубликованные статьи с тегом.
+func GetTagArticles(c echo.Context) error {
+	tag, err := service.GetTagByName(database.DB, tagParam(c))
+	if errors.Is(err, service.ErrTagNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		log.Printf("error getting tag: %s", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	cursor, err := service.DecodeCursor(c.QueryParam("cursor"), service.SortLatest)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	page, err := service.GetArticlesByTag(database.DB, tag.ID, cursor, apiPageSize(c, tagPageSize))
+	if err != nil {
+		log.Printf("error getting articles of tag %d: %s", tag.ID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	c.Response().Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if wantsJSON(c) {
+		return c.JSON(http.StatusOK, dto.FromArticlePage(page))
+	}
+	userID := apiUserID(c)
+	followed, err := service.GetFollowedTagIDs(database.DB, userID)
+	if err != nil {
+		log.Printf("error getting followed tags of user %d: %s", userID, err)
+	}
+	return c.Render(http.StatusOK, "allArticle.html", map[string]interface{}{
+		"articles":        page.Articles,
+		"currentUsername": currentUsername(userID),
+		"tag":             tag.TagContent,
+		"tagFollowed":     followed[tag.ID],
+		"saved":           savedArticles(userID, page.Articles),
+		"next_cursor":     page.NextCursor,
+		"total":           page.Total,
+	})
+}
+
+func FollowTag(c echo.Context) error {
+	return setTagFollowed(c, true)
+}
+
+func UnfollowTag(c echo.Context) error {
+	return setTagFollowed(c, false)
+}
+
+func setTagFollowed(c echo.Context, follow bool) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil || userID == 0 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+	var tag models.Tag
+	if follow {
+		tag, err = service.FollowTag(database.DB, userID, tagParam(c))
+	} else {
+		tag, err = service.UnfollowTag(database.DB, userID, tagParam(c))
+	}
+	if errors.Is(err, service.ErrTagNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		log.Printf("error changing tag follow of user %d: %s", userID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "ошибка на стороне сервера"})
+	}
+	if isFormPost(c) {
+		referer := c.Request().Referer()
+		if referer == "" {
+			referer = "/tags/" + url.PathEscape(tag.TagContent)
+		}
+		return c.Redirect(http.StatusSeeOther, referer)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"tag":       tag.TagContent,
+		"following": follow,
+	})
+}
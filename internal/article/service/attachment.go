@@ -0,0 +1,381 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"maps"
+	"net/http"
+	"news/pkg/models"
+	"news/pkg/storage"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+	"gorm.io/gorm"
+)
+
+// ThumbnailWidths — ширины уменьшенных копий изображений. Копии шире оригинала не создаются.
+var ThumbnailWidths = []int{320, 960}
+
+const (
+	// maxImagePixels защищает от изображений, которые занимают мало байт, но огромны после распаковки.
+	// Для GIF ограничение действует на сумму пикселей всех кадров.
+	maxImagePixels = 40_000_000
+	// maxGIFFrames ограничивает число кадров анимации.
+	maxGIFFrames = 500
+)
+
+var (
+	ErrUnsupportedImage   = errors.New("поддерживаются только изображения JPEG, PNG и GIF")
+	ErrImageTooLarge      = errors.New("слишком большое разрешение изображения")
+	ErrAttachmentNotFound = errors.New("вложение не найдено")
+)
+
+// imageFormats — допустимые типы содержимого, определённые по сигнатуре файла, и расширения для них.
+var imageFormats = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// processedImage — изображение, перекодированное без метаданных, и его уменьшенные копии.
+type processedImage struct {
+	ContentType string
+	Ext         string
+	Data        []byte
+	Width       int
+	Height      int
+	Thumbnails  map[int][]byte
+}
+
+// processImage проверяет тип изображения по сигнатуре, а не по заголовку запроса или расширению,
+// и перекодирует его. При перекодировании отбрасываются EXIF и прочие метаданные, включая геолокацию.
+func processImage(data []byte) (processedImage, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := imageFormats[contentType]
+	if !ok {
+		return processedImage{}, ErrUnsupportedImage
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return processedImage{}, ErrUnsupportedImage
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return processedImage{}, ErrImageTooLarge
+	}
+
+	result := processedImage{
+		ContentType: contentType,
+		Ext:         ext,
+		Width:       config.Width,
+		Height:      config.Height,
+		Thumbnails:  make(map[int][]byte),
+	}
+	var first image.Image
+	var buf bytes.Buffer
+	if contentType == "image/gif" {
+		// GIF перекодируется целиком, чтобы сохранить анимацию. DecodeConfig видит только размер
+		// логического экрана, поэтому кадры проверяются до распаковки
+		if err := checkGIFFrames(data); err != nil {
+			return processedImage{}, err
+		}
+		animation, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return processedImage{}, ErrUnsupportedImage
+		}
+		if err := gif.EncodeAll(&buf, animation); err != nil {
+			return processedImage{}, fmt.Errorf("ошибка при обработке изображения: %w", err)
+		}
+		first = animation.Image[0]
+	} else {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return processedImage{}, ErrUnsupportedImage
+		}
+		if contentType == "image/jpeg" {
+			img = applyOrientation(img, jpegOrientation(data))
+			result.Width, result.Height = img.Bounds().Dx(), img.Bounds().Dy()
+		}
+		if err := encodeImage(&buf, img, contentType); err != nil {
+			return processedImage{}, err
+		}
+		first = img
+	}
+	result.Data = buf.Bytes()
+
+	for _, width := range ThumbnailWidths {
+		if width >= result.Width {
+			continue
+		}
+		height := result.Height * width / result.Width
+		if height < 1 {
+			height = 1
+		}
+		thumb := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(thumb, thumb.Bounds(), first, first.Bounds(), draw.Over, nil)
+		var out bytes.Buffer
+		// Уменьшенная копия GIF статична и хранится в PNG
+		thumbType := contentType
+		if thumbType == "image/gif" {
+			thumbType = "image/png"
+		}
+		if err := encodeImage(&out, thumb, thumbType); err != nil {
+			return processedImage{}, err
+		}
+		result.Thumbnails[width] = out.Bytes()
+	}
+	return result, nil
+}
+
+// checkGIFFrames проходит по блокам GIF без распаковки и проверяет число кадров и их суммарную площадь.
+func checkGIFFrames(data []byte) error {
+	// Заголовок (6 байт) и дескриптор логического экрана (7 байт)
+	if len(data) < 13 {
+		return ErrUnsupportedImage
+	}
+	pos := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << (flags&0x07 + 1)
+	}
+	skipSubBlocks := func() bool {
+		for pos < len(data) {
+			size := int(data[pos])
+			pos += 1 + size
+			if size == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	frames, pixels := 0, 0
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // расширение: метка и подблоки
+			pos += 2
+			if !skipSubBlocks() {
+				return ErrUnsupportedImage
+			}
+		case 0x2C: // дескриптор кадра
+			if pos+10 > len(data) {
+				return ErrUnsupportedImage
+			}
+			width := int(data[pos+5]) | int(data[pos+6])<<8
+			height := int(data[pos+7]) | int(data[pos+8])<<8
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			frames++
+			pixels += width * height
+			if frames > maxGIFFrames || pixels > maxImagePixels {
+				return ErrImageTooLarge
+			}
+			// Минимальный размер кода LZW, затем сжатые данные подблоками
+			pos++
+			if !skipSubBlocks() {
+				return ErrUnsupportedImage
+			}
+		case 0x3B: // конец файла
+			return nil
+		default:
+			return ErrUnsupportedImage
+		}
+	}
+	return nil
+}
+
+func encodeImage(buf *bytes.Buffer, img image.Image, contentType string) error {
+	var err error
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(buf, img)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка при обработке изображения: %w", err)
+	}
+	return nil
+}
+
+// storageKeyPattern находит в тексте статьи ключи загруженных изображений и их уменьшенных копий.
+var storageKeyPattern = regexp.MustCompile(`images/\d{4}/\d{2}/[0-9a-f]{32}(?:_w\d+)?\.(?:jpg|png|gif)`)
+
+// validStorageKey проверяет, что путь запроса целиком является ключом изображения.
+var validStorageKey = regexp.MustCompile(`^` + storageKeyPattern.String() + `$`)
+
+// originalKeys возвращает возможные ключи оригинала для ключа оригинала или уменьшенной копии:
+// копия images/2025/01/<hex>_w320.jpg относится к оригиналу images/2025/01/<hex> с любым из расширений.
+func originalKeys(key string) []string {
+	base := key[:len(key)-len(path.Ext(key))]
+	if i := strings.LastIndex(base, "_w"); i != -1 {
+		base = base[:i]
+	}
+	keys := make([]string, 0, len(imageFormats))
+	for _, ext := range imageFormats {
+		keys = append(keys, base+"."+ext)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// linkAttachments привязывает к статье изображения автора, на которые ссылается её текст и которые
+// ещё не привязаны к другой статье. После этого файлы видны только тем, кто может видеть статью.
+func linkAttachments(tx *gorm.DB, article models.Article, content string) error {
+	var keys []string
+	for _, key := range storageKeyPattern.FindAllString(content, -1) {
+		keys = append(keys, originalKeys(key)...)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	err := tx.Model(&models.Attachment{}).
+		Where("uploader_id = ? AND article_id IS NULL AND storage_key IN ?", article.AuthorID, keys).
+		Update("article_id", article.ID).Error
+	if err != nil {
+		return fmt.Errorf("ошибка при привязке вложений к статье: %w", err)
+	}
+	return nil
+}
+
+// GetVisibleAttachment находит вложение по ключу оригинала или уменьшенной копии. Вложение статьи
+// доступно тем, кто может видеть статью, и загрузившему его пользователю; остальным возвращается
+// ErrAttachmentNotFound. Непривязанное вложение доступно всем: его ключ случаен и известен только
+// загрузившему, пока тот не вставит изображение в статью. public сообщает, что статья опубликована
+// и файл можно хранить в общих кэшах.
+func GetVisibleAttachment(db *gorm.DB, key string, userID uint) (attachment models.Attachment, public bool, err error) {
+	if !validStorageKey.MatchString(key) {
+		return models.Attachment{}, false, ErrAttachmentNotFound
+	}
+	if err := db.Where("storage_key IN ?", originalKeys(key)).First(&attachment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Attachment{}, false, ErrAttachmentNotFound
+		}
+		return models.Attachment{}, false, fmt.Errorf("ошибка при получении вложения: %w", err)
+	}
+	if attachment.StorageKey != key && !slices.Contains(slices.Collect(maps.Values(attachment.Thumbnails)), key) {
+		return models.Attachment{}, false, ErrAttachmentNotFound
+	}
+	if attachment.ArticleID == nil {
+		return attachment, false, nil
+	}
+	article, err := GetArticleRef(db, uint64(*attachment.ArticleID))
+	if err != nil {
+		return models.Attachment{}, false, err
+	}
+	if !CanViewArticle(article, userID) && attachment.UploaderID != userID {
+		return models.Attachment{}, false, ErrAttachmentNotFound
+	}
+	return attachment, article.Status == models.ArticleStatusPublished, nil
+}
+
+// newStorageKey возвращает случайное имя файла без расширения в каталоге текущего месяца: images/2025/01/<hex>.
+func newStorageKey() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("images/%s/%s", time.Now().UTC().Format("2006/01"), hex.EncodeToString(random)), nil
+}
+
+// SaveAttachment обрабатывает изображение, сохраняет его и уменьшенные копии в хранилище и создаёт запись Attachment.
+// Если указана статья, загружать в неё может только её автор.
+func SaveAttachment(ctx context.Context, db *gorm.DB, store storage.Storage, uploaderID uint, articleID *uint, data []byte) (models.Attachment, error) {
+	if articleID != nil {
+		var article models.Article
+		if err := db.Select("id, author_id").First(&article, *articleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.Attachment{}, ErrArticleNotFound
+			}
+			return models.Attachment{}, fmt.Errorf("ошибка при получении статьи: %w", err)
+		}
+		if article.AuthorID != uploaderID {
+			return models.Attachment{}, ErrNotAuthor
+		}
+	}
+	img, err := processImage(data)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	baseKey, err := newStorageKey()
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("ошибка при создании имени файла: %w", err)
+	}
+
+	key := baseKey + "." + img.Ext
+	stored := []string{key}
+	cleanup := func() {
+		for _, k := range stored {
+			if err := store.Delete(context.Background(), k); err != nil {
+				log.Printf("failed to delete stored file %s: %s", k, err)
+			}
+		}
+	}
+	if err := store.Put(ctx, key, bytes.NewReader(img.Data), img.ContentType); err != nil {
+		return models.Attachment{}, fmt.Errorf("ошибка при сохранении файла: %w", err)
+	}
+	attachment := models.Attachment{
+		UploaderID:  uploaderID,
+		ArticleID:   articleID,
+		StorageKey:  key,
+		ContentType: img.ContentType,
+		Size:        int64(len(img.Data)),
+		Width:       img.Width,
+		Height:      img.Height,
+		Thumbnails:  make(map[int]string),
+	}
+	for width, thumb := range img.Thumbnails {
+		thumbType := http.DetectContentType(thumb)
+		thumbKey := fmt.Sprintf("%s_w%d.%s", baseKey, width, imageFormats[thumbType])
+		if err := store.Put(ctx, thumbKey, bytes.NewReader(thumb), thumbType); err != nil {
+			cleanup()
+			return models.Attachment{}, fmt.Errorf("ошибка при сохранении уменьшенной копии: %w", err)
+		}
+		stored = append(stored, thumbKey)
+		attachment.Thumbnails[width] = thumbKey
+	}
+	if err := db.Create(&attachment).Error; err != nil {
+		cleanup()
+		return models.Attachment{}, fmt.Errorf("ошибка при сохранении вложения: %w", err)
+	}
+	return attachment, nil
+}
+
+// DeleteAttachment удаляет вложение и его файлы. Удалять вложение может только загрузивший его пользователь.
+func DeleteAttachment(ctx context.Context, db *gorm.DB, store storage.Storage, attachmentID uint64, userID uint) error {
+	var attachment models.Attachment
+	if err := db.First(&attachment, attachmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAttachmentNotFound
+		}
+		return fmt.Errorf("ошибка при получении вложения: %w", err)
+	}
+	if attachment.UploaderID != userID {
+		return ErrNotAuthor
+	}
+	if err := db.Delete(&attachment).Error; err != nil {
+		return fmt.Errorf("ошибка при удалении вложения: %w", err)
+	}
+	keys := []string{attachment.StorageKey}
+	for _, key := range attachment.Thumbnails {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			log.Printf("failed to delete stored file %s: %s", key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"slices"
+	"testing"
+)
+
+// gifWithFrames собирает GIF из count кадров размером width×height с пустыми данными LZW.
+func gifWithFrames(count, width, height int) []byte {
+	data := []byte("GIF89a")
+	data = binary.LittleEndian.AppendUint16(data, uint16(width))
+	data = binary.LittleEndian.AppendUint16(data, uint16(height))
+	data = append(data, 0, 0, 0)
+	for range count {
+		data = append(data, 0x2C, 0, 0, 0, 0)
+		data = binary.LittleEndian.AppendUint16(data, uint16(width))
+		data = binary.LittleEndian.AppendUint16(data, uint16(height))
+		data = append(data, 0, 2, 0)
+	}
+	return append(data, 0x3B)
+}
+
+func encodedGIF(t *testing.T) []byte {
+	t.Helper()
+	palette := color.Palette{color.Black, color.White}
+	animation := &gif.GIF{}
+	for range 3 {
+		animation.Image = append(animation.Image, image.NewPaletted(image.Rect(0, 0, 4, 4), palette))
+		animation.Delay = append(animation.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, animation); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCheckGIFFrames(t *testing.T) {
+	valid := encodedGIF(t)
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"настоящая анимация", valid, nil},
+		{"без кадров", gifWithFrames(0, 1, 1), nil},
+		{"предельное число кадров", gifWithFrames(maxGIFFrames, 1, 1), nil},
+		{"слишком много кадров", gifWithFrames(maxGIFFrames+1, 1, 1), ErrImageTooLarge},
+		{"слишком большая сумма площадей", gifWithFrames(2, 5000, 5000), ErrImageTooLarge},
+		{"короткий заголовок", []byte("GIF89a"), ErrUnsupportedImage},
+		{"обрезанный кадр", valid[:len(valid)-3], ErrUnsupportedImage},
+		{"неизвестный блок", append(gifWithFrames(1, 1, 1)[:13], 0x00), ErrUnsupportedImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkGIFFrames(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("checkGIFFrames = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// jpegWithOrientation кодирует JPEG width×height и вставляет после SOI сегмент APP1 с тегом Orientation.
+func jpegWithOrientation(t *testing.T, width, height int, order binary.AppendByteOrder, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatal(err)
+	}
+	tiff := []byte("II")
+	if order == binary.BigEndian {
+		tiff = []byte("MM")
+	}
+	tiff = order.AppendUint16(tiff, 42)
+	tiff = order.AppendUint32(tiff, 8)
+	tiff = order.AppendUint16(tiff, 1)
+	tiff = order.AppendUint16(tiff, 0x0112)
+	tiff = order.AppendUint16(tiff, 3)
+	tiff = order.AppendUint32(tiff, 1)
+	tiff = order.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(segment)+2))
+	data = append(data, segment...)
+	return append(data, buf.Bytes()[2:]...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	var plain bytes.Buffer
+	if err := jpeg.Encode(&plain, image.NewRGBA(image.Rect(0, 0, 2, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+	rotated := jpegWithOrientation(t, 2, 2, binary.LittleEndian, 6)
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"без EXIF", plain.Bytes(), 1},
+		{"Intel", rotated, 6},
+		{"Motorola", jpegWithOrientation(t, 2, 2, binary.BigEndian, 8), 8},
+		{"значение вне диапазона", jpegWithOrientation(t, 2, 2, binary.LittleEndian, 9), 1},
+		{"не JPEG", []byte("GIF89a"), 1},
+		{"пустые данные", nil, 1},
+		{"обрезанный сегмент", rotated[:20], 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// В пикселе исходного изображения 3×2 записаны его координаты: R = x, G = y
+	const w, h = 3, 2
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			src.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+	tests := []struct {
+		orientation   int
+		width, height int
+		srcX, srcY    int
+	}{
+		{1, w, h, 0, 0},
+		{2, w, h, w - 1, 0},
+		{3, w, h, w - 1, h - 1},
+		{4, w, h, 0, h - 1},
+		{5, h, w, 0, 0},
+		{6, h, w, 0, h - 1},
+		{7, h, w, w - 1, h - 1},
+		{8, h, w, w - 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(string(rune('0'+tt.orientation)), func(t *testing.T) {
+			img := applyOrientation(src, tt.orientation)
+			if img.Bounds().Dx() != tt.width || img.Bounds().Dy() != tt.height {
+				t.Fatalf("размер = %v, want %dx%d", img.Bounds().Size(), tt.width, tt.height)
+			}
+			r, g, _, _ := img.At(0, 0).RGBA()
+			if int(r>>8) != tt.srcX || int(g>>8) != tt.srcY {
+				t.Errorf("левый верхний пиксель взят из (%d, %d), want (%d, %d)", r>>8, g>>8, tt.srcX, tt.srcY)
+			}
+		})
+	}
+}
+
+func TestProcessImage(t *testing.T) {
+	var wide bytes.Buffer
+	if err := png.Encode(&wide, image.NewRGBA(image.Rect(0, 0, 400, 10))); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		data          []byte
+		contentType   string
+		width, height int
+		thumbnails    []int
+		err           error
+	}{
+		{"JPEG с поворотом", jpegWithOrientation(t, 4, 2, binary.LittleEndian, 6), "image/jpeg", 2, 4, nil, nil},
+		{"PNG с уменьшенной копией", wide.Bytes(), "image/png", 400, 10, []int{320}, nil},
+		{"GIF", encodedGIF(t), "image/gif", 4, 4, nil, nil},
+		{"не изображение", []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"/>"), "", 0, 0, nil, ErrUnsupportedImage},
+		{"битый PNG", wide.Bytes()[:40], "", 0, 0, nil, ErrUnsupportedImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := processImage(tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("processImage error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			var widths []int
+			for width := range img.Thumbnails {
+				widths = append(widths, width)
+			}
+			slices.Sort(widths)
+			if img.ContentType != tt.contentType || img.Width != tt.width || img.Height != tt.height || !slices.Equal(widths, tt.thumbnails) {
+				t.Errorf("processImage = %s %dx%d %v, want %s %dx%d %v",
+					img.ContentType, img.Width, img.Height, widths, tt.contentType, tt.width, tt.height, tt.thumbnails)
+			}
+		})
+	}
+}
+
+func TestOriginalKeys(t *testing.T) {
+	want := []string{
+		"images/2025/01/9f86d081884c7d659a2feaa0c55ad015.gif",
+		"images/2025/01/9f86d081884c7d659a2feaa0c55ad015.jpg",
+		"images/2025/01/9f86d081884c7d659a2feaa0c55ad015.png",
+	}
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"оригинал", "images/2025/01/9f86d081884c7d659a2feaa0c55ad015.jpg"},
+		{"уменьшенная копия", "images/2025/01/9f86d081884c7d659a2feaa0c55ad015_w320.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := originalKeys(tt.key); !slices.Equal(got, want) {
+				t.Errorf("originalKeys(%q) = %q, want %q", tt.key, got, want)
+			}
+		})
+	}
+}
+
+func TestValidStorageKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"images/2025/01/9f86d081884c7d659a2feaa0c55ad015.jpg", true},
+		{"images/2025/01/9f86d081884c7d659a2feaa0c55ad015_w960.png", true},
+		{"images/2025/01/9f86d081884c7d659a2feaa0c55ad015.svg", false},
+		{"images/2025/01/short.jpg", false},
+		{"../images/2025/01/9f86d081884c7d659a2feaa0c55ad015.jpg", false},
+		{"images/2025/01/9f86d081884c7d659a2feaa0c55ad015.jpg/x", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := validStorageKey.MatchString(tt.key); got != tt.want {
+				t.Errorf("validStorageKey(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"news/pkg/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddBookmark сохраняет видимую пользователю статью в его список для чтения. Повторное сохранение ничего не меняет.
+func AddBookmark(db *gorm.DB, articleID uint64, userID uint) error {
+	if _, err := GetVisibleArticle(db, articleID, userID); err != nil {
+		return err
+	}
+	bookmark := models.Bookmark{UserID: userID, ArticleID: uint(articleID)}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&bookmark).Error; err != nil {
+		return fmt.Errorf("ошибка при добавлении закладки: %w", err)
+	}
+	return nil
+}
+
+// RemoveBookmark убирает статью из списка для чтения пользователя.
+func RemoveBookmark(db *gorm.DB, articleID uint64, userID uint) error {
+	err := db.Where("article_id = ? AND user_id = ?", articleID, userID).Delete(&models.Bookmark{}).Error
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении закладки: %w", err)
+	}
+	return nil
+}
+
+// GetBookmarks возвращает страницу сохранённых пользователем опубликованных статей, начиная с последних сохранённых.
+// Курсор указывает на закладку, а не на статью.
+func GetBookmarks(db *gorm.DB, userID uint, cursor *Cursor, limit int) (ArticlePage, error) {
+	bookmarksQuery := func() *gorm.DB {
+		return db.Model(&models.Bookmark{}).
+			Joins("JOIN articles ON articles.id = bookmarks.article_id AND articles.deleted_at IS NULL").
+			Where("bookmarks.user_id = ? AND articles.status = ?", userID, models.ArticleStatusPublished)
+	}
+
+	var page ArticlePage
+	if err := bookmarksQuery().Count(&page.Total).Error; err != nil {
+		return ArticlePage{}, err
+	}
+
+	query := bookmarksQuery().
+		Select("bookmarks.id, bookmarks.article_id, bookmarks.created_at").
+		Order("bookmarks.created_at DESC, bookmarks.id DESC")
+	if cursor != nil {
+		query = query.Where("(bookmarks.created_at, bookmarks.id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+	var bookmarks []struct {
+		ID        uint
+		ArticleID uint
+		CreatedAt time.Time
+	}
+	if err := query.Limit(limit + 1).Scan(&bookmarks).Error; err != nil {
+		return ArticlePage{}, err
+	}
+	if len(bookmarks) > limit {
+		bookmarks = bookmarks[:limit]
+		last := bookmarks[limit-1]
+		page.NextCursor = EncodeCursor(Cursor{Sort: SortBookmarked, ID: last.ID, CreatedAt: last.CreatedAt})
+	}
+
+	ids := make([]uint, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		ids = append(ids, bookmark.ArticleID)
+	}
+	articles, err := GetArticlesByIDs(db, ids)
+	if err != nil {
+		return ArticlePage{}, err
+	}
+	page.Articles = articles
+	return page, nil
+}
+
+// GetBookmarkedIDs сообщает, какие из указанных статей сохранены пользователем.
+func GetBookmarkedIDs(db *gorm.DB, userID uint, articleIDs []uint) (map[uint]bool, error) {
+	saved := make(map[uint]bool)
+	if userID == 0 || len(articleIDs) == 0 {
+		return saved, nil
+	}
+	var ids []uint
+	err := db.Model(&models.Bookmark{}).
+		Where("user_id = ? AND article_id IN ?", userID, articleIDs).
+		Pluck("article_id", &ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении закладок: %w", err)
+	}
+	for _, id := range ids {
+		saved[id] = true
+	}
+	return saved, nil
+}
+
+// IsBookmarked сообщает, сохранил ли пользователь статью.
+func IsBookmarked(db *gorm.DB, articleID uint64, userID uint) (bool, error) {
+	saved, err := GetBookmarkedIDs(db, userID, []uint{uint(articleID)})
+	if err != nil {
+		return false, err
+	}
+	return saved[uint(articleID)], nil
+}
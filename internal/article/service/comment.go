@@ -0,0 +1,217 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"news/pkg/models"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const maxCommentLength = 5000
+
+var (
+	ErrCommentNotFound  = errors.New("комментарий не найден")
+	ErrNotCommentAuthor = errors.New("изменять комментарий может только его автор")
+	ErrNotArticleAuthor = errors.New("скрывать комментарии может только автор статьи")
+	ErrEmptyComment     = errors.New("комментарий не может быть пустым")
+	ErrCommentTooLong   = fmt.Errorf("комментарий длиннее %d символов", maxCommentLength)
+	ErrInvalidParent    = errors.New("родительский комментарий не найден")
+)
+
+// CommentNode — комментарий с ответами и правами текущего пользователя на него.
+type CommentNode struct {
+	models.Comment
+	Deleted     bool
+	CanEdit     bool // пользователь — автор комментария
+	CanModerate bool // пользователь — автор статьи
+	Replies     []*CommentNode
+}
+
+func validateCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", ErrEmptyComment
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		return "", ErrCommentTooLong
+	}
+	return body, nil
+}
+
+// CreateComment добавляет комментарий к статье, видимой пользователю. parentID задаёт комментарий, на который это ответ.
+func CreateComment(db *gorm.DB, articleID uint64, userID uint, parentID *uint, body string) (models.Comment, error) {
+	body, err := validateCommentBody(body)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if _, err := GetVisibleArticle(db, articleID, userID); err != nil {
+		return models.Comment{}, err
+	}
+	if parentID != nil {
+		var parent models.Comment
+		err := db.Select("id").Where("article_id = ?", articleID).First(&parent, *parentID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Comment{}, ErrInvalidParent
+		}
+		if err != nil {
+			return models.Comment{}, fmt.Errorf("ошибка при получении комментария: %w", err)
+		}
+	}
+	comment := models.Comment{
+		ArticleID: uint(articleID),
+		AuthorID:  userID,
+		ParentID:  parentID,
+		Body:      body,
+	}
+	if err := db.Create(&comment).Error; err != nil {
+		return models.Comment{}, fmt.Errorf("ошибка при создании комментария: %w", err)
+	}
+	return comment, db.Preload("Author", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, username")
+	}).First(&comment, comment.ID).Error
+}
+
+// GetCommentTree возвращает дерево комментариев статьи глазами viewerID. Тексты скрытых
+// комментариев видны только автору статьи и автору комментария, удалённые комментарии
+// остаются в дереве заглушками, только если на них есть ответы.
+func GetCommentTree(db *gorm.DB, article models.Article, viewerID uint) ([]*CommentNode, error) {
+	var comments []models.Comment
+	err := db.Unscoped().
+		Preload("Author", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, username")
+		}).
+		Where("article_id = ?", article.ID).
+		Order("created_at, id").
+		Find(&comments).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении комментариев: %w", err)
+	}
+
+	nodes := make(map[uint]*CommentNode, len(comments))
+	for _, comment := range comments {
+		node := &CommentNode{
+			Comment:     comment,
+			Deleted:     comment.DeletedAt.Valid,
+			CanEdit:     comment.AuthorID == viewerID && !comment.DeletedAt.Valid,
+			CanModerate: article.AuthorID == viewerID && !comment.DeletedAt.Valid,
+		}
+		if node.Deleted || (node.Hidden && !node.CanEdit && !node.CanModerate) {
+			node.Body = ""
+		}
+		nodes[comment.ID] = node
+	}
+	var roots []*CommentNode
+	for _, comment := range comments {
+		node := nodes[comment.ID]
+		if parent, ok := nodes[derefID(comment.ParentID)]; ok {
+			parent.Replies = append(parent.Replies, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return pruneDeleted(roots), nil
+}
+
+func derefID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// pruneDeleted убирает удалённые комментарии, у которых не осталось ответов.
+func pruneDeleted(nodes []*CommentNode) []*CommentNode {
+	kept := nodes[:0]
+	for _, node := range nodes {
+		node.Replies = pruneDeleted(node.Replies)
+		if node.Deleted && len(node.Replies) == 0 {
+			continue
+		}
+		kept = append(kept, node)
+	}
+	return kept
+}
+
+func getComment(db *gorm.DB, commentID uint64) (models.Comment, error) {
+	var comment models.Comment
+	err := db.Preload("Author", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, username")
+	}).First(&comment, commentID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Comment{}, ErrCommentNotFound
+		}
+		return models.Comment{}, fmt.Errorf("ошибка при получении комментария: %w", err)
+	}
+	return comment, nil
+}
+
+// getOwnComment возвращает комментарий пользователя к статье, которую он может видеть. Комментарии
+// к статье, снятой с публикации, недоступны так же, как сама статья.
+func getOwnComment(db *gorm.DB, commentID uint64, userID uint) (models.Comment, error) {
+	comment, err := getComment(db, commentID)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	article, err := GetArticleRef(db, uint64(comment.ArticleID))
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if !CanViewArticle(article, userID) {
+		return models.Comment{}, ErrArticleNotFound
+	}
+	if comment.AuthorID != userID {
+		return models.Comment{}, ErrNotCommentAuthor
+	}
+	return comment, nil
+}
+
+// UpdateComment меняет текст комментария. Изменять комментарий может только его автор.
+func UpdateComment(db *gorm.DB, commentID uint64, userID uint, body string) (models.Comment, error) {
+	body, err := validateCommentBody(body)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	comment, err := getOwnComment(db, commentID, userID)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if err := db.Model(&comment).Update("body", body).Error; err != nil {
+		return models.Comment{}, fmt.Errorf("ошибка при изменении комментария: %w", err)
+	}
+	return comment, nil
+}
+
+// DeleteComment мягко удаляет комментарий его автором и возвращает ID статьи.
+func DeleteComment(db *gorm.DB, commentID uint64, userID uint) (uint, error) {
+	comment, err := getOwnComment(db, commentID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if err := db.Delete(&comment).Error; err != nil {
+		return 0, fmt.Errorf("ошибка при удалении комментария: %w", err)
+	}
+	return comment.ArticleID, nil
+}
+
+// SetCommentHidden скрывает или показывает комментарий. Доступно только автору статьи.
+func SetCommentHidden(db *gorm.DB, commentID uint64, userID uint, hidden bool) (models.Comment, error) {
+	comment, err := getComment(db, commentID)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	var article models.Article
+	if err := db.Select("id, author_id").First(&article, comment.ArticleID).Error; err != nil {
+		return models.Comment{}, fmt.Errorf("ошибка при получении статьи: %w", err)
+	}
+	if article.AuthorID != userID {
+		return models.Comment{}, ErrNotArticleAuthor
+	}
+	if err := db.Model(&comment).Update("hidden", hidden).Error; err != nil {
+		return models.Comment{}, fmt.Errorf("ошибка при изменении комментария: %w", err)
+	}
+	return comment, nil
+}
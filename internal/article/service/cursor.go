@@ -0,0 +1,60 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"news/pkg/models"
+	"slices"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("неверный курсор пагинации")
+
+// Cursor — позиция последнего элемента страницы. Клиенту передаётся в закодированном виде
+// и возвращается без изменений для получения следующей страницы.
+type Cursor struct {
+	Sort        string    `json:"s,omitempty"`
+	ID          uint      `json:"i"`
+	CreatedAt   time.Time `json:"c,omitempty"`
+	PublishedAt time.Time `json:"p,omitempty"`
+	NumViews    int       `json:"v,omitempty"`
+	Rank        float64   `json:"r,omitempty"`
+}
+
+type ArticlePage struct {
+	Articles   []models.Article
+	NextCursor string
+	Total      int64
+}
+
+func EncodeCursor(c Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor разбирает курсор. Пустая строка означает первую страницу. Курсор другого порядка
+// сортировки, чем sorts, отклоняется: его поля не имеют смысла для запрошенного списка.
+func DecodeCursor(s string, sorts ...string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == 0 || !slices.Contains(sorts, c.Sort) {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// ListCursorSorts возвращает порядки, курсоры которых принимает список статей с сортировкой sort.
+// Рейтинг без данных отдаёт последние статьи, поэтому принимает и их курсоры.
+func ListCursorSorts(sort string) []string {
+	if sort == SortLatest || sort == SortMostViewed {
+		return []string{sort}
+	}
+	return []string{SortTrending, SortLatest}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	published := time.Date(2026, 3, 14, 9, 26, 53, 589793000, time.UTC)
+	tests := []struct {
+		name   string
+		cursor Cursor
+		sorts  []string
+	}{
+		{"последние", Cursor{Sort: SortLatest, ID: 42, PublishedAt: published}, []string{SortLatest}},
+		{"популярные", Cursor{Sort: SortMostViewed, ID: 7, NumViews: 1500}, []string{SortMostViewed}},
+		{"рейтинг", Cursor{Sort: SortTrending, ID: 3, Rank: 0.125}, ListCursorSorts(SortTrending)},
+		{"последние в рейтинге", Cursor{Sort: SortLatest, ID: 9, PublishedAt: published}, ListCursorSorts("")},
+		{"поиск", Cursor{Sort: SortRelevance, ID: 5, CreatedAt: published, Rank: 0.5}, []string{SortRelevance}},
+		{"закладки", Cursor{Sort: SortBookmarked, ID: 11, CreatedAt: published}, []string{SortBookmarked}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(EncodeCursor(tt.cursor), tt.sorts...)
+			if err != nil {
+				t.Fatalf("DecodeCursor: %v", err)
+			}
+			if got.Sort != tt.cursor.Sort || got.ID != tt.cursor.ID || got.NumViews != tt.cursor.NumViews ||
+				got.Rank != tt.cursor.Rank || !got.PublishedAt.Equal(tt.cursor.PublishedAt) || !got.CreatedAt.Equal(tt.cursor.CreatedAt) {
+				t.Errorf("DecodeCursor = %+v, want %+v", *got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorEmpty(t *testing.T) {
+	cursor, err := DecodeCursor("", SortLatest)
+	if cursor != nil || err != nil {
+		t.Errorf("DecodeCursor(\"\") = %v, %v, want nil, nil", cursor, err)
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+		sorts  []string
+	}{
+		{"не base64", "!!!", []string{SortLatest}},
+		{"не JSON", base64.RawURLEncoding.EncodeToString([]byte("cursor")), []string{SortLatest}},
+		{"без ID", EncodeCursor(Cursor{Sort: SortLatest}), []string{SortLatest}},
+		{"популярные в последних", EncodeCursor(Cursor{Sort: SortMostViewed, ID: 1}), []string{SortLatest}},
+		{"последние в популярных", EncodeCursor(Cursor{Sort: SortLatest, ID: 1}), ListCursorSorts(SortMostViewed)},
+		{"популярные в рейтинге", EncodeCursor(Cursor{Sort: SortMostViewed, ID: 1}), ListCursorSorts(SortTrending)},
+		{"поиск в списке", EncodeCursor(Cursor{Sort: SortRelevance, ID: 1}), ListCursorSorts(SortLatest)},
+		{"закладки в ленте", EncodeCursor(Cursor{Sort: SortBookmarked, ID: 1}), []string{SortLatest}},
+		{"без сортировки", EncodeCursor(Cursor{ID: 1}), []string{SortRelevance}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.cursor, tt.sorts...); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"news/pkg/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUserNotFound = errors.New("пользователь не найден")
+	ErrSelfFollow   = errors.New("нельзя подписаться на самого себя")
+)
+
+// FollowAuthor подписывает пользователя на автора. Повторная подписка ничего не меняет.
+func FollowAuthor(db *gorm.DB, followerID, authorID uint) error {
+	if followerID == authorID {
+		return ErrSelfFollow
+	}
+	var author models.User
+	if err := db.Select("id").First(&author, authorID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	follow := models.Follow{FollowerID: followerID, AuthorID: authorID}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error; err != nil {
+		return fmt.Errorf("ошибка при подписке на автора: %w", err)
+	}
+	return nil
+}
+
+// UnfollowAuthor отменяет подписку пользователя на автора.
+func UnfollowAuthor(db *gorm.DB, followerID, authorID uint) error {
+	err := db.Where("follower_id = ? AND author_id = ?", followerID, authorID).Delete(&models.Follow{}).Error
+	if err != nil {
+		return fmt.Errorf("ошибка при отписке от автора: %w", err)
+	}
+	return nil
+}
+
+// IsFollowing сообщает, подписан ли пользователь на автора.
+func IsFollowing(db *gorm.DB, followerID, authorID uint) (bool, error) {
+	if followerID == 0 {
+		return false, nil
+	}
+	var count int64
+	err := db.Model(&models.Follow{}).
+		Where("follower_id = ? AND author_id = ?", followerID, authorID).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("ошибка при проверке подписки: %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetFollowedAuthors возвращает авторов, на которых подписан пользователь.
+func GetFollowedAuthors(db *gorm.DB, userID uint) ([]models.User, error) {
+	var authors []models.User
+	err := db.Select("users.id, users.username").
+		Joins("JOIN follows ON follows.author_id = users.id").
+		Where("follows.follower_id = ?", userID).
+		Order("users.username").
+		Find(&authors).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении подписок: %w", err)
+	}
+	return authors, nil
+}
+
+// GetFeed возвращает страницу опубликованных статей авторов и тегов, на которые подписан пользователь,
+// начиная с новых.
+func GetFeed(db *gorm.DB, userID uint, cursor *Cursor, limit int) (ArticlePage, error) {
+	feedQuery := func(query *gorm.DB) *gorm.DB {
+		return query.
+			Where("articles.status = ?", models.ArticleStatusPublished).
+			// Скобки обязательны: без них OR отрывает подписки на теги от фильтров статуса, удаления и курсора
+			Where(`(
+            articles.author_id IN (SELECT author_id FROM follows WHERE follower_id = ?) OR
+            articles.id IN (
+                SELECT at.article_id FROM article_tags at
+                JOIN tag_follows tf ON tf.tag_id = at.tag_id
+                WHERE tf.user_id = ?
+            )
+        )`, userID, userID)
+	}
+
+	var page ArticlePage
+	if err := feedQuery(db.Model(&models.Article{})).Count(&page.Total).Error; err != nil {
+		return ArticlePage{}, err
+	}
+
+	query := feedQuery(articleListQuery(db)).Order(publishTimeExpr + " DESC, articles.id DESC")
+	if cursor != nil {
+		query = query.Where("("+publishTimeExpr+", articles.id) < (?, ?)", cursor.PublishedAt, cursor.ID)
+	}
+	var articles []models.Article
+	if err := query.Limit(limit + 1).Find(&articles).Error; err != nil {
+		return ArticlePage{}, err
+	}
+	if len(articles) > limit {
+		articles = articles[:limit]
+		last := articles[limit-1]
+		page.NextCursor = EncodeCursor(Cursor{Sort: SortLatest, ID: last.ID, PublishedAt: publishedAt(last)})
+	}
+	if err := attachReactions(db, articles); err != nil {
+		return ArticlePage{}, err
+	}
+	page.Articles = articles
+	return page, nil
+}
+
+// GetArticlesByAuthor возвращает страницу опубликованных статей автора, начиная с новых.
+func GetArticlesByAuthor(db *gorm.DB, authorID uint, cursor *Cursor, limit int) (ArticlePage, error) {
+	var page ArticlePage
+	err := db.Model(&models.Article{}).
+		Where("status = ? AND author_id = ?", models.ArticleStatusPublished, authorID).
+		Count(&page.Total).Error
+	if err != nil {
+		return ArticlePage{}, err
+	}
+
+	query := articleListQuery(db).
+		Where("articles.author_id = ?", authorID).
+		Order(publishTimeExpr + " DESC, articles.id DESC")
+	if cursor != nil {
+		query = query.Where("("+publishTimeExpr+", articles.id) < (?, ?)", cursor.PublishedAt, cursor.ID)
+	}
+	var articles []models.Article
+	if err := query.Limit(limit + 1).Find(&articles).Error; err != nil {
+		return ArticlePage{}, err
+	}
+	if len(articles) > limit {
+		articles = articles[:limit]
+		last := articles[limit-1]
+		page.NextCursor = EncodeCursor(Cursor{Sort: SortLatest, ID: last.ID, PublishedAt: publishedAt(last)})
+	}
+	if err := attachReactions(db, articles); err != nil {
+		return ArticlePage{}, err
+	}
+	page.Articles = articles
+	return page, nil
+}
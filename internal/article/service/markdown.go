@@ -0,0 +1,64 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"news/pkg/models"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+	"gorm.io/gorm"
+)
+
+// markdown переводит текст статьи в HTML. Сырой HTML в исходнике не пропускается,
+// а переносы строк сохраняются, чтобы статьи, написанные простым текстом, не слиплись в один абзац.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(html.WithHardWraps()),
+)
+
+// contentPolicy — белый список тегов и атрибутов для HTML статей. Скрипты, стили
+// и обработчики событий отбрасываются, ссылки получают rel="nofollow".
+var contentPolicy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	// Класс языка в блоках кода нужен для подсветки синтаксиса
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}()
+
+// RenderMarkdown переводит Markdown в HTML и очищает результат по белому списку contentPolicy.
+func RenderMarkdown(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return "", fmt.Errorf("ошибка при разборе Markdown: %w", err)
+	}
+	return contentPolicy.Sanitize(buf.String()), nil
+}
+
+// BackfillArticleHTML формирует HTML для статей, сохранённых до появления Markdown.
+func BackfillArticleHTML(db *gorm.DB) error {
+	var articles []models.Article
+	err := db.Unscoped().Select("id, article_content").
+		Where("content_html IS NULL").
+		FindInBatches(&articles, 100, func(_ *gorm.DB, _ int) error {
+			for _, article := range articles {
+				contentHTML, err := RenderMarkdown(article.ArticleContent)
+				if err != nil {
+					return err
+				}
+				err = db.Unscoped().Model(&article).UpdateColumn("content_html", contentHTML).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("ошибка при формировании HTML статей: %w", err)
+	}
+	return nil
+}
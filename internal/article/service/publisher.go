@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"news/pkg/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PublishDueArticles публикует запланированные статьи, у которых наступило время PublishAt, и возвращает их ID.
+// Черновики и статьи на рецензии не публикуются, даже если у них осталось время PublishAt.
+// Строки блокируются через FOR UPDATE SKIP LOCKED, поэтому несколько реплик сервиса
+// могут выполнять публикацию одновременно, не обрабатывая одну статью дважды.
+func PublishDueArticles(db *gorm.DB) ([]uint, error) {
+	var published []models.Article
+	err := db.Raw(`
+		UPDATE articles SET status = ?, publish_at = NULL, published_at = COALESCE(published_at, NOW()), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM articles
+			WHERE deleted_at IS NULL
+				AND status = ?
+				AND publish_at IS NOT NULL
+				AND publish_at <= NOW()
+			ORDER BY publish_at
+			LIMIT 100
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id`,
+		models.ArticleStatusPublished, models.ArticleStatusScheduled,
+	).Scan(&published).Error
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(published))
+	for _, article := range published {
+		ids = append(ids, article.ID)
+	}
+	return ids, nil
+}
+
+// BackfillPublishedAt проставляет время публикации статьям, опубликованным до появления published_at.
+// Для них лучшее известное приближение — время создания.
+func BackfillPublishedAt(db *gorm.DB) error {
+	err := db.Model(&models.Article{}).Unscoped().
+		Where("status = ? AND published_at IS NULL", models.ArticleStatusPublished).
+		Update("published_at", gorm.Expr("created_at")).Error
+	if err != nil {
+		return fmt.Errorf("ошибка при заполнении времени публикации статей: %w", err)
+	}
+	return nil
+}
+
+// RunScheduledPublisher периодически публикует запланированные статьи, пока не будет отменён ctx.
+// onPublished вызывается с ID опубликованных статей, например для сброса кеша.
+func RunScheduledPublisher(ctx context.Context, db *gorm.DB, interval time.Duration, onPublished func(ctx context.Context, articleIDs []uint)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ids, err := PublishDueArticles(db.WithContext(ctx))
+			if err != nil {
+				log.Printf("error publishing scheduled articles: %s", err)
+				continue
+			}
+			if len(ids) > 0 {
+				log.Printf("published scheduled articles: %v", ids)
+				if onPublished != nil {
+					onPublished(ctx, ids)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"news/pkg/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidReaction = errors.New("неизвестный вид реакции")
+
+// ReactionKinds — допустимые виды реакций в порядке отображения.
+var ReactionKinds = []string{
+	models.ReactionLike,
+	models.ReactionDislike,
+	models.ReactionLove,
+	models.ReactionLaugh,
+	models.ReactionWow,
+	models.ReactionSad,
+}
+
+func IsValidReaction(kind string) bool {
+	for _, k := range ReactionKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ToggleReaction ставит реакцию пользователя на видимую ему статью или снимает уже поставленную.
+// Возвращает, стоит ли реакция после вызова, и обновлённые счётчики статьи.
+func ToggleReaction(db *gorm.DB, articleID uint64, userID uint, kind string) (bool, map[string]int64, error) {
+	if !IsValidReaction(kind) {
+		return false, nil, ErrInvalidReaction
+	}
+	if _, err := GetVisibleArticle(db, articleID, userID); err != nil {
+		return false, nil, err
+	}
+	var reacted bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("article_id = ? AND user_id = ? AND kind = ?", articleID, userID, kind).
+			Delete(&models.ArticleReaction{})
+		if res.Error != nil {
+			return fmt.Errorf("ошибка при удалении реакции: %w", res.Error)
+		}
+		if res.RowsAffected > 0 {
+			return nil
+		}
+		reaction := models.ArticleReaction{ArticleID: uint(articleID), UserID: userID, Kind: kind}
+		// Параллельный запрос мог уже поставить ту же реакцию — уникальный индекс не даст её продублировать
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reaction).Error; err != nil {
+			return fmt.Errorf("ошибка при добавлении реакции: %w", err)
+		}
+		reacted = true
+		return nil
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	counts, err := GetReactionCounts(db, []uint{uint(articleID)})
+	if err != nil {
+		return false, nil, err
+	}
+	return reacted, counts[uint(articleID)], nil
+}
+
+// GetReactionCounts возвращает число реакций каждого вида для указанных статей.
+func GetReactionCounts(db *gorm.DB, articleIDs []uint) (map[uint]map[string]int64, error) {
+	counts := make(map[uint]map[string]int64, len(articleIDs))
+	if len(articleIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		ArticleID uint
+		Kind      string
+		Count     int64
+	}
+	err := db.Model(&models.ArticleReaction{}).
+		Select("article_id, kind, COUNT(*) AS count").
+		Where("article_id IN ?", articleIDs).
+		Group("article_id, kind").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при подсчёте реакций: %w", err)
+	}
+	for _, row := range rows {
+		if counts[row.ArticleID] == nil {
+			counts[row.ArticleID] = make(map[string]int64)
+		}
+		counts[row.ArticleID][row.Kind] = row.Count
+	}
+	return counts, nil
+}
+
+// GetUserReactions возвращает виды реакций, которые пользователь поставил статье.
+func GetUserReactions(db *gorm.DB, articleID uint64, userID uint) (map[string]bool, error) {
+	reacted := make(map[string]bool)
+	if userID == 0 {
+		return reacted, nil
+	}
+	var kinds []string
+	err := db.Model(&models.ArticleReaction{}).
+		Where("article_id = ? AND user_id = ?", articleID, userID).
+		Pluck("kind", &kinds).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении реакций пользователя: %w", err)
+	}
+	for _, kind := range kinds {
+		reacted[kind] = true
+	}
+	return reacted, nil
+}
+
+// attachReactions заполняет счётчики реакций у статей.
+func attachReactions(db *gorm.DB, articles []models.Article) error {
+	ids := make([]uint, 0, len(articles))
+	for _, article := range articles {
+		ids = append(ids, article.ID)
+	}
+	counts, err := GetReactionCounts(db, ids)
+	if err != nil {
+		return err
+	}
+	for i := range articles {
+		articles[i].Reactions = counts[articles[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"news/pkg/models"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// RelatedArticlesLimit — число похожих статей на странице статьи.
+	RelatedArticlesLimit = 5
+	// relatedTextWeight задаёт вклад полнотекстового сходства заголовков относительно одного общего тега
+	relatedTextWeight = 10
+	relatedCacheTTL   = time.Hour
+)
+
+func relatedCacheKey(articleID uint64) string {
+	return fmt.Sprintf("article:related:%d", articleID)
+}
+
+// FindRelatedArticleIDs подбирает опубликованные статьи, похожие на данную: по числу общих тегов
+// и по совпадению слов заголовка с search_vector кандидата.
+func FindRelatedArticleIDs(db *gorm.DB, articleID uint64, limit int) ([]uint, error) {
+	var hits []struct {
+		ID    uint
+		Score float64
+	}
+	err := db.Raw(`
+		WITH source_query AS (
+			SELECT NULLIF(array_to_string(ARRAY(
+				SELECT quote_literal(lexeme)
+				FROM unnest(tsvector_to_array(ts_filter(search_vector, '{a}'))) AS lexeme
+			), ' | '), '')::tsquery AS q
+			FROM articles WHERE id = ?
+		), source_tags AS (
+			SELECT tag_id FROM article_tags WHERE article_id = ?
+		)
+		SELECT a.id,
+			(SELECT COUNT(*) FROM article_tags at
+				WHERE at.article_id = a.id AND at.tag_id IN (SELECT tag_id FROM source_tags))
+			+ COALESCE(ts_rank(a.search_vector, sq.q), 0) * ? AS score
+		FROM articles a, source_query sq
+		WHERE a.id <> ? AND a.deleted_at IS NULL AND a.status = ?
+			AND (
+				a.id IN (SELECT article_id FROM article_tags WHERE tag_id IN (SELECT tag_id FROM source_tags))
+				OR a.search_vector @@ sq.q
+			)
+		ORDER BY score DESC, a.created_at DESC, a.id DESC
+		LIMIT ?`,
+		articleID, articleID, relatedTextWeight, articleID, models.ArticleStatusPublished, limit,
+	).Scan(&hits).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при подборе похожих статей: %w", err)
+	}
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids, nil
+}
+
+// GetRelatedArticles возвращает похожие статьи. Подобранные ID кешируются в Redis,
+// сами статьи загружаются заново, чтобы в выдачу не попали снятые с публикации.
+func GetRelatedArticles(ctx context.Context, db *gorm.DB, rdb *redis.Client, articleID uint64) ([]models.Article, error) {
+	key := relatedCacheKey(articleID)
+	var ids []uint
+	cached, err := rdb.Get(ctx, key).Bytes()
+	if err != nil || json.Unmarshal(cached, &ids) != nil {
+		if err != nil && !errors.Is(err, redis.Nil) {
+			log.Printf("error reading related articles of %d from redis: %s", articleID, err)
+		}
+		ids, err = FindRelatedArticleIDs(db, articleID, RelatedArticlesLimit)
+		if err != nil {
+			return nil, err
+		}
+		serialized, _ := json.Marshal(ids)
+		if err := rdb.Set(ctx, key, serialized, relatedCacheTTL).Err(); err != nil {
+			log.Printf("failed to cache related articles of %d: %s", articleID, err)
+		}
+	}
+	return GetArticlesByIDs(db, ids)
+}
+
+// InvalidateRelatedArticles сбрасывает подборки похожих статей, например после изменения их тегов.
+func InvalidateRelatedArticles(ctx context.Context, rdb *redis.Client, articleIDs []uint) error {
+	if len(articleIDs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(articleIDs))
+	for _, id := range articleIDs {
+		keys = append(keys, relatedCacheKey(uint64(id)))
+	}
+	return rdb.Del(ctx, keys...).Err()
+}
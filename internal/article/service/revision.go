@@ -0,0 +1,200 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"news/pkg/models"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrRevisionNotFound = errors.New("ревизия не найдена")
+
+// maxDiffCells ограничивает размер таблицы LCS в DiffLines. Если различающаяся часть текстов больше,
+// она показывается целиком как удалённая и вставленная, чтобы diff больших ревизий не съел всю память.
+const maxDiffCells = 4_000_000
+
+type DiffLine struct {
+	Op   string `json:"op"` // "equal", "insert" или "delete"
+	Text string `json:"text"`
+}
+
+type RevisionDiff struct {
+	From        models.ArticleRevision `json:"from"`
+	To          models.ArticleRevision `json:"to"`
+	TitleDiff   []DiffLine             `json:"title_diff"`
+	ContentDiff []DiffLine             `json:"content_diff"`
+	TagsDiff    []DiffLine             `json:"tags_diff"`
+}
+
+// RecordRevision сохраняет снимок статьи как очередную ревизию. Вызывается внутри транзакции создания или изменения статьи.
+func RecordRevision(tx *gorm.DB, articleID, editorID uint, title, content string, tagNames []string) error {
+	// Блокировка строки статьи упорядочивает одновременные правки, иначе обе получат один номер версии
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Article{}, articleID).Error
+	if err != nil {
+		return fmt.Errorf("ошибка при блокировке статьи: %w", err)
+	}
+	var lastVersion int
+	err = tx.Model(&models.ArticleRevision{}).
+		Where("article_id = ?", articleID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&lastVersion).Error
+	if err != nil {
+		return fmt.Errorf("ошибка при получении версии статьи: %w", err)
+	}
+	revision := models.ArticleRevision{
+		ArticleID:      articleID,
+		Version:        lastVersion + 1,
+		EditorID:       editorID,
+		ArticleTitle:   title,
+		ArticleContent: content,
+		Tags:           strings.Join(tagNames, ","),
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		return fmt.Errorf("ошибка при сохранении ревизии: %w", err)
+	}
+	return nil
+}
+
+func GetRevisions(db *gorm.DB, articleID uint64) ([]models.ArticleRevision, error) {
+	var revisions []models.ArticleRevision
+	err := db.
+		Preload("Editor", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, username")
+		}).
+		Where("article_id = ?", articleID).
+		Order("version DESC").
+		Find(&revisions).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении ревизий: %w", err)
+	}
+	return revisions, nil
+}
+
+func GetRevision(db *gorm.DB, articleID, revisionID uint64) (models.ArticleRevision, error) {
+	var revision models.ArticleRevision
+	err := db.Where("article_id = ?", articleID).First(&revision, revisionID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.ArticleRevision{}, ErrRevisionNotFound
+		}
+		return models.ArticleRevision{}, fmt.Errorf("ошибка при получении ревизии: %w", err)
+	}
+	return revision, nil
+}
+
+// DiffRevisions строит построчный diff между двумя ревизиями одной статьи.
+func DiffRevisions(db *gorm.DB, articleID, fromID, toID uint64) (RevisionDiff, error) {
+	from, err := GetRevision(db, articleID, fromID)
+	if err != nil {
+		return RevisionDiff{}, err
+	}
+	to, err := GetRevision(db, articleID, toID)
+	if err != nil {
+		return RevisionDiff{}, err
+	}
+	return RevisionDiff{
+		From:        from,
+		To:          to,
+		TitleDiff:   DiffLines(splitLines(from.ArticleTitle), splitLines(to.ArticleTitle)),
+		ContentDiff: DiffLines(splitLines(from.ArticleContent), splitLines(to.ArticleContent)),
+		TagsDiff:    DiffLines(sortedTags(from.Tags), sortedTags(to.Tags)),
+	}, nil
+}
+
+// RestoreRevision делает содержимое старой ревизии текущей версией статьи. Восстановление записывается как новая ревизия.
+func RestoreRevision(db *gorm.DB, articleID, revisionID uint64, userID uint) (models.Article, error) {
+	revision, err := GetRevision(db, articleID, revisionID)
+	if err != nil {
+		return models.Article{}, err
+	}
+	return UpdateArticle(db, articleID, userID, revision.ArticleTitle, revision.ArticleContent, revision.Tags)
+}
+
+// DiffLines сравнивает два набора строк через наибольшую общую подпоследовательность.
+// Общие начало и конец отбрасываются до построения таблицы, размер таблицы ограничен maxDiffCells.
+func DiffLines(a, b []string) []DiffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var diff []DiffLine
+	for _, line := range a[:prefix] {
+		diff = append(diff, DiffLine{Op: "equal", Text: line})
+	}
+	diff = append(diff, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		diff = append(diff, DiffLine{Op: "equal", Text: line})
+	}
+	return diff
+}
+
+func diffMiddle(a, b []string) []DiffLine {
+	var diff []DiffLine
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for _, line := range a {
+			diff = append(diff, DiffLine{Op: "delete", Text: line})
+		}
+		for _, line := range b {
+			diff = append(diff, DiffLine{Op: "insert", Text: line})
+		}
+		return diff
+	}
+
+	// Таблица хранится одним срезом int32: строка i занимает элементы [i*width, (i+1)*width)
+	width := len(b) + 1
+	lcs := make([]int32, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else {
+				lcs[i*width+j] = max(lcs[(i+1)*width+j], lcs[i*width+j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: "equal", Text: a[i]})
+			i++
+			j++
+		case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+			diff = append(diff, DiffLine{Op: "delete", Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: "insert", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{Op: "delete", Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{Op: "insert", Text: b[j]})
+	}
+	return diff
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
+
+func sortedTags(tags string) []string {
+	tagNames := splitTagNames(tags)
+	sort.Strings(tagNames)
+	return tagNames
+}
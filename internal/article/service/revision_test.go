@@ -0,0 +1,92 @@
+package service
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	eq := func(text string) DiffLine { return DiffLine{Op: "equal", Text: text} }
+	ins := func(text string) DiffLine { return DiffLine{Op: "insert", Text: text} }
+	del := func(text string) DiffLine { return DiffLine{Op: "delete", Text: text} }
+	tests := []struct {
+		name string
+		a, b []string
+		want []DiffLine
+	}{
+		{"пустые", nil, nil, nil},
+		{"одинаковые", []string{"a", "b"}, []string{"a", "b"}, []DiffLine{eq("a"), eq("b")}},
+		{"вставка в пустой", nil, []string{"a"}, []DiffLine{ins("a")}},
+		{"удаление всего", []string{"a", "b"}, nil, []DiffLine{del("a"), del("b")}},
+		{"вставка в середину", []string{"a", "c"}, []string{"a", "b", "c"}, []DiffLine{eq("a"), ins("b"), eq("c")}},
+		{"удаление из середины", []string{"a", "b", "c"}, []string{"a", "c"}, []DiffLine{eq("a"), del("b"), eq("c")}},
+		{"замена строки", []string{"a", "b", "c"}, []string{"a", "x", "c"}, []DiffLine{eq("a"), del("b"), ins("x"), eq("c")}},
+		{
+			"общая подпоследовательность",
+			[]string{"a", "b", "c", "d"},
+			[]string{"b", "x", "d", "e"},
+			[]DiffLine{del("a"), eq("b"), del("c"), ins("x"), eq("d"), ins("e")},
+		},
+		{"кириллица", []string{"Привет", "мир"}, []string{"Привет", "Мир"}, []DiffLine{eq("Привет"), del("мир"), ins("Мир")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffLines(tt.a, tt.b); !slices.Equal(got, tt.want) {
+				t.Errorf("DiffLines(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesLargeChange(t *testing.T) {
+	// Различающаяся часть больше maxDiffCells: она целиком удаляется и вставляется, общие края сохраняются
+	size := 2100
+	a := make([]string, 0, size+2)
+	b := make([]string, 0, size+2)
+	a = append(a, "начало")
+	b = append(b, "начало")
+	for i := range size {
+		a = append(a, "old"+string(rune('a'+i%26)))
+		b = append(b, "new"+string(rune('a'+i%26)))
+	}
+	a = append(a, "конец")
+	b = append(b, "конец")
+
+	diff := DiffLines(a, b)
+	if len(diff) != 2*size+2 {
+		t.Fatalf("len(DiffLines) = %d, want %d", len(diff), 2*size+2)
+	}
+	if diff[0] != (DiffLine{Op: "equal", Text: "начало"}) || diff[len(diff)-1] != (DiffLine{Op: "equal", Text: "конец"}) {
+		t.Errorf("общие края = %v, %v", diff[0], diff[len(diff)-1])
+	}
+	for i, line := range diff[1 : size+1] {
+		if line.Op != "delete" || line.Text != a[i+1] {
+			t.Fatalf("diff[%d] = %v, want delete %q", i+1, line, a[i+1])
+		}
+	}
+	for i, line := range diff[size+1 : 2*size+1] {
+		if line.Op != "insert" || line.Text != b[i+1] {
+			t.Fatalf("diff[%d] = %v, want insert %q", size+i+1, line, b[i+1])
+		}
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"пустой текст", "", nil},
+		{"одна строка", "a", []string{"a"}},
+		{"LF", "a\nb", []string{"a", "b"}},
+		{"CRLF", "a\r\nb\r\n", []string{"a", "b", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitLines(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("splitLines(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
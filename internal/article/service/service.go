@@ -5,33 +5,226 @@ import (
 	"fmt"
 	"log"
 	"news/pkg/models"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func GetArticlesWithDetails(db *gorm.DB) ([]models.Article, error) {
-	var articles []models.Article
+var (
+	ErrArticleNotFound         = errors.New("статья не найдена")
+	ErrNotAuthor               = errors.New("у вас нет прав для изменения данной записи")
+	ErrInvalidStatus           = errors.New("неизвестный статус статьи")
+	ErrInvalidStatusTransition = errors.New("недопустимый переход статуса статьи")
+	ErrPublishAtInPast         = errors.New("время публикации должно быть в будущем")
+	ErrPublishAtNotPublished   = errors.New("отложить можно только публикацию статьи")
+)
+
+// articleStatusTransitions описывает, в какие статусы можно перевести статью из текущего.
+var articleStatusTransitions = map[string][]string{
+	models.ArticleStatusDraft:     {models.ArticleStatusInReview, models.ArticleStatusPublished, models.ArticleStatusArchived},
+	models.ArticleStatusInReview:  {models.ArticleStatusDraft, models.ArticleStatusPublished},
+	models.ArticleStatusPublished: {models.ArticleStatusDraft, models.ArticleStatusArchived},
+	models.ArticleStatusArchived:  {models.ArticleStatusDraft},
+	models.ArticleStatusScheduled: {models.ArticleStatusDraft, models.ArticleStatusPublished, models.ArticleStatusArchived},
+}
+
+// ValidateInitialStatus проверяет статус новой статьи. Пустой статус означает черновик.
+func ValidateInitialStatus(status string) (string, error) {
+	switch status {
+	case "":
+		return models.ArticleStatusDraft, nil
+	case models.ArticleStatusDraft, models.ArticleStatusInReview, models.ArticleStatusPublished:
+		return status, nil
+	}
+	return "", ErrInvalidStatus
+}
+
+// ResolveInitialStatus проверяет статус и время публикации новой статьи. Статья с временем
+// отложенной публикации получает статус scheduled; отложить можно только публикацию,
+// черновик или статья на рецензии публикуются автором вручную.
+func ResolveInitialStatus(status string, publishAt *time.Time) (string, error) {
+	if publishAt == nil {
+		return ValidateInitialStatus(status)
+	}
+	if status != "" && status != models.ArticleStatusPublished {
+		if _, err := ValidateInitialStatus(status); err != nil {
+			return "", err
+		}
+		return "", ErrPublishAtNotPublished
+	}
+	if !publishAt.After(time.Now()) {
+		return "", ErrPublishAtInPast
+	}
+	return models.ArticleStatusScheduled, nil
+}
+
+func CanTransition(from, to string) bool {
+	for _, allowed := range articleStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
 
-	err := db.
-		Select("articles.id, articles.article_title, articles.article_content, articles.author_id, articles.created_at").
+// CanViewArticle сообщает, может ли пользователь видеть статью: неопубликованные статьи доступны только автору.
+func CanViewArticle(article models.Article, userID uint) bool {
+	return article.Status == models.ArticleStatusPublished || article.AuthorID == userID
+}
+
+// PopularArticlesLimit — число статей на странице популярных новостей.
+const PopularArticlesLimit = 10
+
+// articleListColumns — колонки статей в списках. HTML статьи в списки не входит.
+const articleListColumns = "articles.id, articles.article_title, articles.slug, articles.article_content, articles.author_id, articles.status, articles.published_at, articles.num_views, articles.created_at, articles.updated_at"
+
+// publishTimeExpr — время публикации статьи в SQL. Запланированная статья появляется в лентах
+// в момент публикации, а не создания; у старых статей без published_at берётся время создания.
+const publishTimeExpr = "COALESCE(articles.published_at, articles.created_at)"
+
+func articleListQuery(db *gorm.DB) *gorm.DB {
+	return db.
+		Select(articleListColumns).
 		Preload("Author", func(db *gorm.DB) *gorm.DB {
 			return db.Select("id, username")
 		}).
 		Preload("Tags", func(db *gorm.DB) *gorm.DB {
 			return db.Select("id, tag_content")
 		}).
-		Order("id DESC").
-		Limit(10).
-		Find(&articles).Error
+		Where("articles.status = ?", models.ArticleStatusPublished)
+}
+
+// GetArticlesWithDetails возвращает страницу опубликованных статей в порядке sort (SortLatest или SortMostViewed).
+func GetArticlesWithDetails(db *gorm.DB, sort string, cursor *Cursor, limit int) (ArticlePage, error) {
+	var page ArticlePage
+	err := db.Model(&models.Article{}).
+		Where("status = ?", models.ArticleStatusPublished).
+		Count(&page.Total).Error
+	if err != nil {
+		return ArticlePage{}, err
+	}
+
+	query := articleListQuery(db)
+	if sort == SortMostViewed {
+		query = query.Order("articles.num_views DESC, articles.id DESC")
+		if cursor != nil {
+			query = query.Where("(articles.num_views, articles.id) < (?, ?)", cursor.NumViews, cursor.ID)
+		}
+	} else {
+		sort = SortLatest
+		query = query.Order(publishTimeExpr + " DESC, articles.id DESC")
+		if cursor != nil {
+			query = query.Where("("+publishTimeExpr+", articles.id) < (?, ?)", cursor.PublishedAt, cursor.ID)
+		}
+	}
+
+	var articles []models.Article
+	if err := query.Limit(limit + 1).Find(&articles).Error; err != nil {
+		return ArticlePage{}, err
+	}
+	if len(articles) > limit {
+		articles = articles[:limit]
+		last := articles[limit-1]
+		page.NextCursor = EncodeCursor(Cursor{Sort: sort, ID: last.ID, PublishedAt: publishedAt(last), NumViews: last.NumViews})
+	}
+	if err := attachReactions(db, articles); err != nil {
+		return ArticlePage{}, err
+	}
+	page.Articles = articles
+	return page, nil
+}
 
+// SearchArticles ищет опубликованные статьи по полнотекстовому индексу и тегам, упорядочивая по релевантности.
+func SearchArticles(db *gorm.DB, searchQuery string, cursor *Cursor, limit int) (ArticlePage, error) {
+	const rankExpr = "ts_rank(search_vector, phraseto_tsquery('russian', ?))"
+	matchQuery := func() *gorm.DB {
+		// Используем phraseto_tsquery для поиска точной фразы
+		return db.Model(&models.Article{}).
+			Where("articles.status = ?", models.ArticleStatusPublished).
+			// Скобки обязательны: без них OR отрывает поиск по тегам от фильтров статуса, удаления и курсора
+			Where(`(
+            search_vector @@ phraseto_tsquery('russian', ?) OR
+            articles.id IN (
+                SELECT at.article_id FROM article_tags at
+                JOIN tags t ON t.id = at.tag_id
+                WHERE to_tsvector('russian', t.tag_content) @@ phraseto_tsquery('russian', ?)
+            )
+        )`, searchQuery, searchQuery)
+	}
+
+	var page ArticlePage
+	if err := matchQuery().Count(&page.Total).Error; err != nil {
+		return ArticlePage{}, err
+	}
+
+	query := matchQuery().
+		Select("articles.id, articles.created_at, "+rankExpr+" AS rank", searchQuery).
+		Order("rank DESC, articles.created_at DESC, articles.id DESC")
+	if cursor != nil {
+		query = query.Where("("+rankExpr+", articles.created_at, articles.id) < (?, ?, ?)",
+			searchQuery, cursor.Rank, cursor.CreatedAt, cursor.ID)
+	}
+	var hits []struct {
+		ID        uint
+		CreatedAt time.Time
+		Rank      float64
+	}
+	if err := query.Limit(limit + 1).Scan(&hits).Error; err != nil {
+		return ArticlePage{}, err
+	}
+	if len(hits) > limit {
+		hits = hits[:limit]
+		last := hits[limit-1]
+		page.NextCursor = EncodeCursor(Cursor{Sort: SortRelevance, ID: last.ID, CreatedAt: last.CreatedAt, Rank: last.Rank})
+	}
+
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	articles, err := GetArticlesByIDs(db, ids)
 	if err != nil {
+		return ArticlePage{}, err
+	}
+	page.Articles = articles
+	return page, nil
+}
+
+// GetArticlesByIDs возвращает опубликованные статьи с указанными ID в том же порядке.
+func GetArticlesByIDs(db *gorm.DB, ids []uint) ([]models.Article, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var found []models.Article
+	if err := articleListQuery(db).Where("articles.id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Article, len(found))
+	for _, article := range found {
+		byID[article.ID] = article
+	}
+	articles := make([]models.Article, 0, len(found))
+	for _, id := range ids {
+		if article, ok := byID[id]; ok {
+			articles = append(articles, article)
+		}
+	}
+	if err := attachReactions(db, articles); err != nil {
 		return nil, err
 	}
 	return articles, nil
 }
 
+// DeleteArticleByID удаляет статью вместе с закладками на неё.
 func DeleteArticleByID(db *gorm.DB, articleID uint64) error {
-	err := db.Where("id = ?", articleID).Delete(&models.Article{}).Error
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("article_id = ?", articleID).Delete(&models.Bookmark{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", articleID).Delete(&models.Article{}).Error
+	})
 	if err != nil {
 		log.Printf("error delete article with id %d:%s", articleID, err)
 		return err
@@ -39,14 +232,261 @@ func DeleteArticleByID(db *gorm.DB, articleID uint64) error {
 	return nil
 }
 
+// DeleteArticle удаляет статью, если userID — её автор.
+func DeleteArticle(db *gorm.DB, articleID uint64, userID uint) error {
+	var article models.Article
+	if err := db.Select("id, author_id").First(&article, articleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrArticleNotFound
+		}
+		return fmt.Errorf("ошибка при получении статьи: %w", err)
+	}
+	if article.AuthorID != userID {
+		return ErrNotAuthor
+	}
+	return DeleteArticleByID(db, articleID)
+}
+
 func GetArticleByIDFromDB(db *gorm.DB, articleID uint64) (models.Article, error) {
 	var article models.Article
 	err := db.Preload("Author").Preload("Tags").First(&article, articleID).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return models.Article{}, fmt.Errorf("статья с ID %d не найдена ", articleID)
+			return models.Article{}, fmt.Errorf("статья с ID %d: %w", articleID, ErrArticleNotFound)
 		}
 		return models.Article{}, fmt.Errorf("ошибка при получении статьи: %s", err)
 	}
+	counts, err := GetReactionCounts(db, []uint{article.ID})
+	if err != nil {
+		return models.Article{}, err
+	}
+	article.Reactions = counts[article.ID]
+	return article, nil
+}
+
+// ParseTagNames разбирает строку тегов через запятую, нормализуя имена и убирая пустые значения и дубликаты.
+func ParseTagNames(inputTags string) ([]string, error) {
+	uniqueTags := make(map[string]bool)
+	var tagsToProcess []string
+	for _, tagName := range splitTagNames(inputTags) {
+		tagName, err := NormalizeTagName(tagName)
+		if err != nil {
+			return nil, err
+		}
+		if tagName != "" && !uniqueTags[tagName] {
+			uniqueTags[tagName] = true
+			tagsToProcess = append(tagsToProcess, tagName)
+		}
+	}
+	return tagsToProcess, nil
+}
+
+func splitTagNames(inputTags string) []string {
+	var tagNames []string
+	for _, tagName := range strings.Split(inputTags, ",") {
+		if tagName = strings.TrimSpace(tagName); tagName != "" {
+			tagNames = append(tagNames, tagName)
+		}
+	}
+	return tagNames
+}
+
+// UpsertTags возвращает теги с указанными нормализованными именами, создавая недостающие.
+// Псевдонимы заменяются тегами, на которые они указывают; повторы в результате убираются.
+func UpsertTags(tx *gorm.DB, tagNames []string) ([]models.Tag, error) {
+	if len(tagNames) == 0 {
+		return nil, nil
+	}
+	existingTagMap, err := resolveTags(tx, tagNames)
+	if err != nil {
+		return nil, err
+	}
+	var newTags []models.Tag
+	for _, tagname := range tagNames {
+		if _, exists := existingTagMap[tagname]; !exists {
+			newTags = append(newTags, models.Tag{TagContent: tagname})
+		}
+	}
+	if len(newTags) > 0 {
+		if err := tx.Create(&newTags).Error; err != nil {
+			return nil, fmt.Errorf("ошибка при создании тегов: %w", err)
+		}
+		for _, tag := range newTags {
+			existingTagMap[tag.TagContent] = tag
+		}
+	}
+	var articleTags []models.Tag
+	added := make(map[uint]bool)
+	for _, tagname := range tagNames {
+		tag := existingTagMap[tagname]
+		if !added[tag.ID] {
+			added[tag.ID] = true
+			articleTags = append(articleTags, tag)
+		}
+	}
+	return articleTags, nil
+}
+
+func tagNamesOf(tags []models.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.TagContent)
+	}
+	return names
+}
+
+// CreateArticle создаёт статью вместе с тегами и первой ревизией в одной транзакции.
+func CreateArticle(db *gorm.DB, authorID uint, title, content, inputTags, status string, publishAt *time.Time) (models.Article, error) {
+	contentHTML, err := RenderMarkdown(content)
+	if err != nil {
+		return models.Article{}, err
+	}
+	article := models.Article{
+		AuthorID:       authorID,
+		ArticleTitle:   title,
+		ArticleContent: content,
+		ContentHTML:    contentHTML,
+		Status:         status,
+		PublishAt:      publishAt,
+	}
+	if status == models.ArticleStatusPublished {
+		now := time.Now()
+		article.PublishedAt = &now
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// slug зависит от ID статьи, поэтому выдаётся после вставки; до этого колонка остаётся NULL
+		if err := tx.Omit("Slug").Create(&article).Error; err != nil {
+			return fmt.Errorf("не удалось создать статью: %w", err)
+		}
+		if err := assignSlug(tx, &article, title); err != nil {
+			return err
+		}
+		tagNames, err := ParseTagNames(inputTags)
+		if err != nil {
+			return err
+		}
+		tags, err := UpsertTags(tx, tagNames)
+		if err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			if err := tx.Model(&article).Association("Tags").Append(tags); err != nil {
+				return fmt.Errorf("ошибка при связывании тега со статьей: %w", err)
+			}
+		}
+		if err := linkAttachments(tx, article, content); err != nil {
+			return err
+		}
+		return RecordRevision(tx, article.ID, authorID, title, content, tagNamesOf(tags))
+	})
+	if err != nil {
+		return models.Article{}, err
+	}
+	return GetArticleByIDFromDB(db, uint64(article.ID))
+}
+
+// UpdateArticle обновляет заголовок, содержание и теги статьи. Изменять статью может только её автор.
+func UpdateArticle(db *gorm.DB, articleID uint64, userID uint, title, content, inputTags string) (models.Article, error) {
+	contentHTML, err := RenderMarkdown(content)
+	if err != nil {
+		return models.Article{}, err
+	}
+	var article models.Article
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&article, articleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrArticleNotFound
+			}
+			return fmt.Errorf("ошибка при получении статьи: %w", err)
+		}
+		if article.AuthorID != userID {
+			return ErrNotAuthor
+		}
+		if article.ArticleTitle != title {
+			if err := assignSlug(tx, &article, title); err != nil {
+				return err
+			}
+		}
+		err := tx.Model(&article).Updates(map[string]interface{}{
+			"article_title":   title,
+			"article_content": content,
+			"content_html":    contentHTML,
+			"updated_at":      time.Now(),
+		}).Error
+		if err != nil {
+			return fmt.Errorf("ошибка при обновлении статьи: %w", err)
+		}
+		tagNames, err := ParseTagNames(inputTags)
+		if err != nil {
+			return err
+		}
+		tags, err := UpsertTags(tx, tagNames)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&article).Association("Tags").Replace(tags); err != nil {
+			return fmt.Errorf("ошибка при связывании тегов со статьей: %w", err)
+		}
+		if err := linkAttachments(tx, article, content); err != nil {
+			return err
+		}
+		return RecordRevision(tx, article.ID, userID, title, content, tagNamesOf(tags))
+	})
+	if err != nil {
+		return models.Article{}, err
+	}
+	return GetArticleByIDFromDB(db, articleID)
+}
+
+// GetVisibleArticle возвращает статью, если пользователь имеет право её видеть, иначе ErrArticleNotFound.
+func GetVisibleArticle(db *gorm.DB, articleID uint64, userID uint) (models.Article, error) {
+	article, err := GetArticleByIDFromDB(db, articleID)
+	if err != nil {
+		return models.Article{}, err
+	}
+	if !CanViewArticle(article, userID) {
+		return models.Article{}, ErrArticleNotFound
+	}
 	return article, nil
 }
+
+// ChangeArticleStatus переводит статью в новый статус с проверкой допустимости перехода. Менять статус может только автор.
+func ChangeArticleStatus(db *gorm.DB, articleID uint64, userID uint, status string) (models.Article, error) {
+	if _, ok := articleStatusTransitions[status]; !ok {
+		return models.Article{}, ErrInvalidStatus
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var article models.Article
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&article, articleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrArticleNotFound
+			}
+			return fmt.Errorf("ошибка при получении статьи: %w", err)
+		}
+		if article.AuthorID != userID {
+			return ErrNotAuthor
+		}
+		if !CanTransition(article.Status, status) {
+			return ErrInvalidStatusTransition
+		}
+		// Любая ручная смена статуса отменяет отложенную публикацию
+		updates := map[string]interface{}{
+			"status":     status,
+			"publish_at": nil,
+			"updated_at": time.Now(),
+		}
+		if status == models.ArticleStatusPublished {
+			// Повторная публикация после снятия статьи не меняет дату первой публикации
+			updates["published_at"] = gorm.Expr("COALESCE(published_at, NOW())")
+		}
+		err := tx.Model(&article).Updates(updates).Error
+		if err != nil {
+			return fmt.Errorf("ошибка при изменении статуса статьи: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Article{}, err
+	}
+	return GetArticleByIDFromDB(db, articleID)
+}
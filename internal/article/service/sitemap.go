@@ -0,0 +1,99 @@
+package service
+
+import (
+	"encoding/xml"
+	"fmt"
+	"news/pkg/models"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SitemapPageSize — число статей в одном файле карты сайта. Протокол допускает до 50 000 адресов.
+const SitemapPageSize = 5000
+
+const sitemapNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// SitemapPage — одна страница карты сайта и время последнего изменения статей на ней.
+type SitemapPage struct {
+	Number       int
+	LastModified time.Time
+}
+
+// GetSitemapPages делит опубликованные статьи на страницы карты сайта в порядке ID.
+func GetSitemapPages(db *gorm.DB) ([]SitemapPage, error) {
+	var pages []SitemapPage
+	err := db.Raw(`
+		SELECT page AS number, MAX(updated_at) AS last_modified
+		FROM (
+			SELECT (ROW_NUMBER() OVER (ORDER BY id) - 1) / ? + 1 AS page, updated_at
+			FROM articles
+			WHERE deleted_at IS NULL AND status = ?
+		) numbered
+		GROUP BY page
+		ORDER BY page`,
+		SitemapPageSize, models.ArticleStatusPublished,
+	).Scan(&pages).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при построении карты сайта: %w", err)
+	}
+	return pages, nil
+}
+
+// GetSitemapArticles возвращает адреса и время изменения опубликованных статей на странице page (с 1).
+func GetSitemapArticles(db *gorm.DB, page int) ([]models.Article, error) {
+	var articles []models.Article
+	err := db.Model(&models.Article{}).
+		Select("id, slug, updated_at").
+		Where("status = ?", models.ArticleStatusPublished).
+		Order("id").
+		Offset((page - 1) * SitemapPageSize).
+		Limit(SitemapPageSize).
+		Find(&articles).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении статей для карты сайта: %w", err)
+	}
+	return articles, nil
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	NS       string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name       `xml:"urlset"`
+	NS      string         `xml:"xmlns,attr"`
+	URLs    []sitemapEntry `xml:"url"`
+}
+
+// RenderSitemapIndex формирует sitemap.xml со ссылками на страницы карты сайта.
+func RenderSitemapIndex(baseURL string, pages []SitemapPage) ([]byte, error) {
+	index := sitemapIndex{NS: sitemapNS}
+	for _, page := range pages {
+		index.Sitemaps = append(index.Sitemaps, sitemapEntry{
+			Loc:     baseURL + "/sitemaps/" + strconv.Itoa(page.Number) + ".xml",
+			LastMod: page.LastModified.UTC().Format(time.RFC3339),
+		})
+	}
+	return marshalXML(index)
+}
+
+// RenderSitemap формирует страницу карты сайта с адресами статей.
+func RenderSitemap(baseURL string, articles []models.Article) ([]byte, error) {
+	set := sitemapURLSet{NS: sitemapNS}
+	for _, article := range articles {
+		set.URLs = append(set.URLs, sitemapEntry{
+			Loc:     articleURL(baseURL, article),
+			LastMod: article.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return marshalXML(set)
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"news/pkg/models"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// maxSlugLength ограничивает slug без числового суффикса; колонка articles.slug вмещает и суффикс.
+	maxSlugLength = 80
+	// slugAttempts — сколько суффиксов -2, -3... пробуется перед тем, как взять суффиксом ID статьи.
+	slugAttempts = 20
+	// defaultSlug используется, если в заголовке нет ни одной буквы или цифры.
+	defaultSlug = "article"
+)
+
+var cyrillicTranslit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+}
+
+// Slugify строит slug из заголовка: кириллица транслитерируется, диакритика отбрасывается,
+// остальные символы заменяются дефисами. Slug из одних цифр получает префикс, чтобы не совпасть с ID статьи.
+func Slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if latin, ok := cyrillicTranslit[r]; ok {
+			if latin == "" {
+				continue
+			}
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteString(latin)
+			continue
+		}
+		// Буквы с диакритикой раскладываются на базовую букву и отбрасываемый знак
+		for _, d := range norm.NFKD.String(string(r)) {
+			switch {
+			case unicode.Is(unicode.Mn, d):
+			case d >= 'a' && d <= 'z', d >= '0' && d <= '9':
+				if dash && b.Len() > 0 {
+					b.WriteByte('-')
+				}
+				dash = false
+				b.WriteRune(d)
+			default:
+				dash = true
+			}
+		}
+	}
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+		if i := strings.LastIndexByte(slug, '-'); i > maxSlugLength/2 {
+			slug = slug[:i]
+		}
+		slug = strings.TrimRight(slug, "-")
+	}
+	if slug == "" {
+		return defaultSlug
+	}
+	if strings.Trim(slug, "0123456789") == "" {
+		return defaultSlug + "-" + slug
+	}
+	return slug
+}
+
+// slugTaken проверяет, занят ли slug другой статьей: текущим адресом, в том числе удалённой статьи, или прежним.
+func slugTaken(tx *gorm.DB, slug string, articleID uint) (bool, error) {
+	var count int64
+	err := tx.Unscoped().Model(&models.Article{}).Where("slug = ? AND id <> ?", slug, articleID).Count(&count).Error
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+	err = tx.Model(&models.ArticleSlug{}).Where("slug = ? AND article_id <> ?", slug, articleID).Count(&count).Error
+	return count > 0, err
+}
+
+// slugCandidate возвращает attempt-й вариант slug: base, base-2, base-3... и в конце base-<ID статьи>.
+func slugCandidate(base string, attempt int, articleID uint) string {
+	switch {
+	case attempt == 1:
+		return base
+	case attempt <= slugAttempts:
+		return base + "-" + strconv.Itoa(attempt)
+	default:
+		return base + "-" + strconv.FormatUint(uint64(articleID), 10)
+	}
+}
+
+// isUniqueViolation сообщает, что запись нарушила уникальный индекс.
+func isUniqueViolation(tx *gorm.DB, err error) bool {
+	translator, ok := tx.Dialector.(gorm.ErrorTranslator)
+	return ok && errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey)
+}
+
+// assignSlug выдаёт статье свободный slug по заголовку, добавляя числовой суффикс при совпадении.
+// Прежний slug сохраняется в article_slugs, чтобы старые ссылки продолжали работать.
+// Проверка и запись slug не атомарны: если его успела занять статья из параллельной транзакции,
+// запись откатывается до точки сохранения и пробуется следующий вариант.
+func assignSlug(tx *gorm.DB, article *models.Article, title string) error {
+	base := Slugify(title)
+	for attempt := 1; attempt <= slugAttempts+1; attempt++ {
+		candidate := slugCandidate(base, attempt, article.ID)
+		if attempt <= slugAttempts {
+			taken, err := slugTaken(tx, candidate, article.ID)
+			if err != nil {
+				return fmt.Errorf("ошибка при проверке slug: %w", err)
+			}
+			if taken {
+				continue
+			}
+		}
+		if candidate == article.Slug {
+			return nil
+		}
+		if err := tx.SavePoint("assign_slug").Error; err != nil {
+			return fmt.Errorf("ошибка при обновлении slug: %w", err)
+		}
+		if err := tx.Model(article).UpdateColumn("slug", candidate).Error; err != nil {
+			if !isUniqueViolation(tx, err) {
+				return fmt.Errorf("ошибка при обновлении slug: %w", err)
+			}
+			if err := tx.RollbackTo("assign_slug").Error; err != nil {
+				return fmt.Errorf("ошибка при обновлении slug: %w", err)
+			}
+			continue
+		}
+		if article.Slug != "" {
+			old := models.ArticleSlug{Slug: article.Slug, ArticleID: article.ID}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&old).Error; err != nil {
+				return fmt.Errorf("ошибка при сохранении прежнего slug: %w", err)
+			}
+		}
+		// Статья могла вернуться к одному из прежних заголовков
+		if err := tx.Where("slug = ? AND article_id = ?", candidate, article.ID).Delete(&models.ArticleSlug{}).Error; err != nil {
+			return fmt.Errorf("ошибка при обновлении slug: %w", err)
+		}
+		article.Slug = candidate
+		return nil
+	}
+	return fmt.Errorf("не удалось подобрать свободный slug для статьи %d", article.ID)
+}
+
+// ResolveArticleSlug находит статью по текущему или прежнему slug.
+// Возвращает ID статьи и её текущий slug: если он отличается от запрошенного, клиента нужно перенаправить.
+func ResolveArticleSlug(db *gorm.DB, slug string) (uint, string, error) {
+	var article models.Article
+	err := db.Select("id, slug").Where("slug = ?", slug).Take(&article).Error
+	if err == nil {
+		return article.ID, article.Slug, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, "", fmt.Errorf("ошибка при поиске статьи по slug: %w", err)
+	}
+	err = db.Select("articles.id, articles.slug").
+		Joins("JOIN article_slugs ON article_slugs.article_id = articles.id").
+		Where("article_slugs.slug = ?", slug).
+		Take(&article).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, "", ErrArticleNotFound
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("ошибка при поиске статьи по slug: %w", err)
+	}
+	return article.ID, article.Slug, nil
+}
+
+// GetArticleRef возвращает ID, slug, статус и автора статьи — достаточно, чтобы проверить доступ и построить адрес.
+func GetArticleRef(db *gorm.DB, articleID uint64) (models.Article, error) {
+	var article models.Article
+	if err := db.Select("id, slug, status, author_id").First(&article, articleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Article{}, ErrArticleNotFound
+		}
+		return models.Article{}, fmt.Errorf("ошибка при получении статьи: %w", err)
+	}
+	return article, nil
+}
+
+// BackfillArticleSlugs выдаёт slug статьям, созданным до появления slug.
+func BackfillArticleSlugs(db *gorm.DB) error {
+	var articles []models.Article
+	err := db.Unscoped().Select("id, article_title, slug").
+		Where("slug IS NULL OR slug = ''").
+		FindInBatches(&articles, 100, func(_ *gorm.DB, _ int) error {
+			for i := range articles {
+				err := db.Transaction(func(tx *gorm.DB) error {
+					return assignSlug(tx.Unscoped(), &articles[i], articles[i].ArticleTitle)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("ошибка при заполнении slug статей: %w", err)
+	}
+	return nil
+}
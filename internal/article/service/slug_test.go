@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"латиница", "Hello, World!", "hello-world"},
+		{"кириллица", "Привет, мир", "privet-mir"},
+		{"многобуквенная транслитерация", "Щука и ёжик в Хабаровске", "shchuka-i-yozhik-v-khabarovske"},
+		{"твёрдый и мягкий знаки", "Подъезд, сьемка и тень", "podezd-semka-i-ten"},
+		{"украинские буквы", "Їжак і ґанок є", "yizhak-i-ganok-ye"},
+		{"заглавные", "МОСКВА", "moskva"},
+		{"смешанный текст", "Go 1.25 вышел", "go-1-25-vyshel"},
+		{"диакритика", "Café crème", "cafe-creme"},
+		{"лишние разделители", "  --Новости--  дня  ", "novosti-dnya"},
+		{"без букв и цифр", "!!! ???", defaultSlug},
+		{"пустой заголовок", "", defaultSlug},
+		{"только цифры", "2025", defaultSlug + "-2025"},
+		{"мягкий знак не разделяет", "Ь", defaultSlug},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Slugify(tt.title); got != tt.want {
+				t.Errorf("Slugify(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlugifyLength(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"обрезка по дефису", strings.Repeat("слово ", 20), strings.TrimSuffix(strings.Repeat("slovo-", 13), "-")},
+		{"обрезка длинного слова", strings.Repeat("я", 100), strings.Repeat("ya", maxSlugLength/2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Slugify(tt.title)
+			if got != tt.want {
+				t.Errorf("Slugify(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+			if len(got) > maxSlugLength {
+				t.Errorf("len(Slugify) = %d, больше %d", len(got), maxSlugLength)
+			}
+		})
+	}
+}
+
+func TestSlugCandidate(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    string
+	}{
+		{1, "novosti"},
+		{2, "novosti-2"},
+		{slugAttempts, fmt.Sprintf("novosti-%d", slugAttempts)},
+		{slugAttempts + 1, "novosti-512"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := slugCandidate("novosti", tt.attempt, 512); got != tt.want {
+				t.Errorf("slugCandidate(%d) = %q, want %q", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	db := &gorm.DB{Config: &gorm.Config{Dialector: postgres.New(postgres.Config{})}}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"нарушение уникальности", &pgconn.PgError{Code: "23505"}, true},
+		{"нарушение внешнего ключа", &pgconn.PgError{Code: "23503"}, false},
+		{"другая ошибка", errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUniqueViolation(db, tt.err); got != tt.want {
+				t.Errorf("isUniqueViolation(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"news/pkg/models"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	FeedFormatRSS  = "rss"
+	FeedFormatAtom = "atom"
+
+	// SyndicationFeedLimit — число статей в RSS- и Atom-лентах.
+	SyndicationFeedLimit = 20
+
+	// feedVersionKey увеличивается при каждом изменении опубликованных статей: закешированные
+	// ленты прошлых версий больше не читаются и удаляются по TTL.
+	feedVersionKey = "feeds:version"
+	feedCacheTTL   = time.Hour
+)
+
+// FeedScope задаёт, какие статьи попадают в ленту: все, с тегом или одного автора.
+type FeedScope struct {
+	Tag      *models.Tag
+	AuthorID uint
+}
+
+func (s FeedScope) cacheKey() string {
+	switch {
+	case s.Tag != nil:
+		return "tag:" + strconv.FormatUint(uint64(s.Tag.ID), 10)
+	case s.AuthorID != 0:
+		return "author:" + strconv.FormatUint(uint64(s.AuthorID), 10)
+	}
+	return "all"
+}
+
+// RenderedFeed — готовый XML ленты с валидаторами для условных запросов.
+type RenderedFeed struct {
+	Body         []byte    `json:"body"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// FeedMeta описывает ленту целиком: заголовок, ссылки и адрес сайта для абсолютных URL.
+type FeedMeta struct {
+	Title       string
+	Description string
+	BaseURL     string
+	// Path — путь страницы, которую представляет лента, например /tags/go
+	Path string
+	// SelfPath — путь самой ленты
+	SelfPath string
+}
+
+// GetFeedArticles возвращает последние опубликованные статьи для ленты по времени публикации.
+func GetFeedArticles(db *gorm.DB, scope FeedScope, limit int) ([]models.Article, error) {
+	query := articleListQuery(db).
+		Select(articleListColumns + ", articles.content_html").
+		Order("COALESCE(articles.published_at, articles.created_at) DESC, articles.id DESC")
+	switch {
+	case scope.Tag != nil:
+		query = query.Where("articles.id IN (SELECT article_id FROM article_tags WHERE tag_id = ?)", scope.Tag.ID)
+	case scope.AuthorID != 0:
+		query = query.Where("articles.author_id = ?", scope.AuthorID)
+	}
+	var articles []models.Article
+	if err := query.Limit(limit).Find(&articles).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при получении статей ленты: %w", err)
+	}
+	return articles, nil
+}
+
+// GetAuthor возвращает пользователя с указанным ID.
+func GetAuthor(db *gorm.DB, userID uint) (models.User, error) {
+	var user models.User
+	if err := db.Select("id, username").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.User{}, ErrUserNotFound
+		}
+		return models.User{}, fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	return user, nil
+}
+
+// GetSyndicationFeed возвращает ленту из кеша Redis или строит её по данным из БД и кеширует.
+// Без Redis лента строится на каждый запрос.
+func GetSyndicationFeed(ctx context.Context, db *gorm.DB, rdb *redis.Client, format string, scope FeedScope, meta FeedMeta) (RenderedFeed, error) {
+	if rdb == nil {
+		return buildSyndicationFeed(db, format, scope, meta)
+	}
+	version, err := rdb.Get(ctx, feedVersionKey).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return buildSyndicationFeed(db, format, scope, meta)
+	}
+	// Путь ленты входит в ключ: одну ленту можно запросить по разным адресам, например по синониму тега,
+	// а ссылка на саму ленту в XML должна совпадать с запрошенной
+	key := fmt.Sprintf("feeds:v%d:%s:%s:%s", version, format, scope.cacheKey(), meta.SelfPath)
+	var feed RenderedFeed
+	if cached, err := rdb.Get(ctx, key).Bytes(); err == nil && json.Unmarshal(cached, &feed) == nil {
+		return feed, nil
+	}
+	feed, err = buildSyndicationFeed(db, format, scope, meta)
+	if err != nil {
+		return RenderedFeed{}, err
+	}
+	if serialized, err := json.Marshal(feed); err == nil {
+		rdb.Set(ctx, key, serialized, feedCacheTTL)
+	}
+	return feed, nil
+}
+
+// InvalidateSyndicationFeeds сбрасывает все закешированные ленты.
+func InvalidateSyndicationFeeds(ctx context.Context, rdb *redis.Client) error {
+	return rdb.Incr(ctx, feedVersionKey).Err()
+}
+
+func buildSyndicationFeed(db *gorm.DB, format string, scope FeedScope, meta FeedMeta) (RenderedFeed, error) {
+	articles, err := GetFeedArticles(db, scope, SyndicationFeedLimit)
+	if err != nil {
+		return RenderedFeed{}, err
+	}
+	var lastModified time.Time
+	for _, article := range articles {
+		if article.UpdatedAt.After(lastModified) {
+			lastModified = article.UpdatedAt
+		}
+	}
+	if lastModified.IsZero() {
+		lastModified = time.Now()
+	}
+	lastModified = lastModified.UTC().Truncate(time.Second)
+
+	var body []byte
+	if format == FeedFormatAtom {
+		body, err = renderAtom(articles, meta, lastModified)
+	} else {
+		body, err = renderRSS(articles, meta, lastModified)
+	}
+	if err != nil {
+		return RenderedFeed{}, fmt.Errorf("ошибка при формировании ленты: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	return RenderedFeed{
+		Body:         body,
+		ETag:         `"` + hex.EncodeToString(sum[:16]) + `"`,
+		LastModified: lastModified,
+	}, nil
+}
+
+// feedContent возвращает HTML статьи, а для статей без него — исходный текст.
+func feedContent(article models.Article) string {
+	if article.ContentHTML != "" {
+		return article.ContentHTML
+	}
+	return article.ArticleContent
+}
+
+// publishedAt возвращает время публикации статьи; у статей без него — время создания.
+func publishedAt(article models.Article) time.Time {
+	if article.PublishedAt != nil {
+		return *article.PublishedAt
+	}
+	return article.CreatedAt
+}
+
+func articleURL(baseURL string, article models.Article) string {
+	return baseURL + article.URLPath()
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	SelfLink      atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Description string   `xml:"description"`
+	Categories  []string `xml:"category"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func renderRSS(articles []models.Article, meta FeedMeta, lastModified time.Time) ([]byte, error) {
+	channel := rssChannel{
+		Title:         meta.Title,
+		Link:          meta.BaseURL + meta.Path,
+		Description:   meta.Description,
+		Language:      "ru",
+		LastBuildDate: lastModified.Format(time.RFC1123Z),
+		SelfLink:      atomLink{Href: meta.BaseURL + meta.SelfPath, Rel: "self", Type: "application/rss+xml"},
+	}
+	for _, article := range articles {
+		link := articleURL(meta.BaseURL, article)
+		channel.Items = append(channel.Items, rssItem{
+			Title:       article.ArticleTitle,
+			Link:        link,
+			GUID:        rssGUID{IsPermaLink: true, Value: link},
+			PubDate:     publishedAt(article).UTC().Format(time.RFC1123Z),
+			Description: feedContent(article),
+			Categories:  tagNamesOf(article.Tags),
+		})
+	}
+	return marshalXML(rssFeed{Version: "2.0", AtomNS: "http://www.w3.org/2005/Atom", Channel: channel})
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     atomPerson     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomText       `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func renderAtom(articles []models.Article, meta FeedMeta, lastModified time.Time) ([]byte, error) {
+	feed := atomFeed{
+		Title:   meta.Title,
+		ID:      meta.BaseURL + meta.SelfPath,
+		Updated: lastModified.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: meta.BaseURL + meta.SelfPath, Rel: "self", Type: "application/atom+xml"},
+			{Href: meta.BaseURL + meta.Path, Rel: "alternate", Type: "text/html"},
+		},
+	}
+	for _, article := range articles {
+		link := articleURL(meta.BaseURL, article)
+		var categories []atomCategory
+		for _, tag := range article.Tags {
+			categories = append(categories, atomCategory{Term: tag.TagContent})
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:      article.ArticleTitle,
+			ID:         link,
+			Link:       atomLink{Href: link, Rel: "alternate", Type: "text/html"},
+			Published:  publishedAt(article).UTC().Format(time.RFC3339),
+			Updated:    article.UpdatedAt.UTC().Format(time.RFC3339),
+			Author:     atomPerson{Name: article.Author.Username},
+			Categories: categories,
+			Content:    atomText{Type: "html", Body: feedContent(article)},
+		})
+	}
+	return marshalXML(feed)
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
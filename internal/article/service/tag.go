@@ -0,0 +1,375 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"news/pkg/models"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxTagLength совпадает с размером колонки tags.tag_content.
+const maxTagLength = 50
+
+var (
+	ErrTagNotFound  = errors.New("тег не найден")
+	ErrTagTooLong   = fmt.Errorf("имя тега длиннее %d символов", maxTagLength)
+	ErrEmptyTag     = errors.New("имя тега не может быть пустым")
+	ErrTagMergeSelf = errors.New("нельзя объединить тег с самим собой")
+	ErrAliasIsTag   = errors.New("псевдоним совпадает с именем существующего тега")
+)
+
+// NormalizeTagName приводит имя тега к канонической форме: Unicode NFC, без учёта регистра,
+// с одиночными пробелами между словами. Пустое имя остаётся пустым.
+func NormalizeTagName(name string) (string, error) {
+	name = norm.NFC.String(cases.Fold().String(norm.NFC.String(name)))
+	name = strings.Join(strings.Fields(name), " ")
+	if utf8.RuneCountInString(name) > maxTagLength {
+		return "", ErrTagTooLong
+	}
+	return name, nil
+}
+
+// resolveTags находит существующие теги по нормализованным именам и псевдонимам.
+// Результат индексирован запрошенными именами.
+func resolveTags(tx *gorm.DB, tagNames []string) (map[string]models.Tag, error) {
+	resolved := make(map[string]models.Tag, len(tagNames))
+	var aliases []models.TagAlias
+	if err := tx.Preload("Tag").Where("alias IN ?", tagNames).Find(&aliases).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при поиске псевдонимов тегов: %w", err)
+	}
+	for _, alias := range aliases {
+		resolved[alias.Alias] = alias.Tag
+	}
+	var tags []models.Tag
+	if err := tx.Where("tag_content IN ?", tagNames).Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при поиске тегов: %w", err)
+	}
+	for _, tag := range tags {
+		resolved[tag.TagContent] = tag
+	}
+	return resolved, nil
+}
+
+// TagSummary — тег с числом опубликованных статей.
+type TagSummary struct {
+	ID           uint   `json:"id"`
+	TagContent   string `json:"tag"`
+	ArticleCount int64  `json:"article_count"`
+}
+
+// GetTagSummaries возвращает теги, у которых есть опубликованные статьи, по убыванию числа статей.
+func GetTagSummaries(db *gorm.DB) ([]TagSummary, error) {
+	var tags []TagSummary
+	err := db.Model(&models.Tag{}).
+		Select("tags.id, tags.tag_content, COUNT(articles.id) AS article_count").
+		Joins("JOIN article_tags at ON at.tag_id = tags.id").
+		Joins("JOIN articles ON articles.id = at.article_id AND articles.deleted_at IS NULL").
+		Where("articles.status = ?", models.ArticleStatusPublished).
+		Group("tags.id, tags.tag_content").
+		Order("article_count DESC, tags.tag_content").
+		Scan(&tags).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении тегов: %w", err)
+	}
+	return tags, nil
+}
+
+// GetTagByName возвращает тег по имени или псевдониму в любой форме записи.
+func GetTagByName(db *gorm.DB, name string) (models.Tag, error) {
+	name, err := NormalizeTagName(name)
+	if err != nil {
+		return models.Tag{}, ErrTagNotFound
+	}
+	resolved, err := resolveTags(db, []string{name})
+	if err != nil {
+		return models.Tag{}, err
+	}
+	tag, ok := resolved[name]
+	if !ok {
+		return models.Tag{}, ErrTagNotFound
+	}
+	return tag, nil
+}
+
+// GetArticlesByTag возвращает страницу опубликованных статей с тегом, начиная с новых.
+func GetArticlesByTag(db *gorm.DB, tagID uint, cursor *Cursor, limit int) (ArticlePage, error) {
+	const taggedCond = "articles.id IN (SELECT article_id FROM article_tags WHERE tag_id = ?)"
+	var page ArticlePage
+	err := db.Model(&models.Article{}).
+		Where("status = ?", models.ArticleStatusPublished).
+		Where(taggedCond, tagID).
+		Count(&page.Total).Error
+	if err != nil {
+		return ArticlePage{}, err
+	}
+
+	query := articleListQuery(db).
+		Where(taggedCond, tagID).
+		Order(publishTimeExpr + " DESC, articles.id DESC")
+	if cursor != nil {
+		query = query.Where("("+publishTimeExpr+", articles.id) < (?, ?)", cursor.PublishedAt, cursor.ID)
+	}
+	var articles []models.Article
+	if err := query.Limit(limit + 1).Find(&articles).Error; err != nil {
+		return ArticlePage{}, err
+	}
+	if len(articles) > limit {
+		articles = articles[:limit]
+		last := articles[limit-1]
+		page.NextCursor = EncodeCursor(Cursor{Sort: SortLatest, ID: last.ID, PublishedAt: publishedAt(last)})
+	}
+	if err := attachReactions(db, articles); err != nil {
+		return ArticlePage{}, err
+	}
+	page.Articles = articles
+	return page, nil
+}
+
+// FollowTag подписывает пользователя на тег: статьи с ним попадут в его ленту.
+func FollowTag(db *gorm.DB, userID uint, name string) (models.Tag, error) {
+	tag, err := GetTagByName(db, name)
+	if err != nil {
+		return models.Tag{}, err
+	}
+	follow := models.TagFollow{UserID: userID, TagID: tag.ID}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error; err != nil {
+		return models.Tag{}, fmt.Errorf("ошибка при подписке на тег: %w", err)
+	}
+	return tag, nil
+}
+
+// UnfollowTag отменяет подписку пользователя на тег.
+func UnfollowTag(db *gorm.DB, userID uint, name string) (models.Tag, error) {
+	tag, err := GetTagByName(db, name)
+	if err != nil {
+		return models.Tag{}, err
+	}
+	err = db.Where("user_id = ? AND tag_id = ?", userID, tag.ID).Delete(&models.TagFollow{}).Error
+	if err != nil {
+		return models.Tag{}, fmt.Errorf("ошибка при отписке от тега: %w", err)
+	}
+	return tag, nil
+}
+
+// GetFollowedTagIDs возвращает ID тегов, на которые подписан пользователь.
+func GetFollowedTagIDs(db *gorm.DB, userID uint) (map[uint]bool, error) {
+	followed := make(map[uint]bool)
+	if userID == 0 {
+		return followed, nil
+	}
+	var ids []uint
+	if err := db.Model(&models.TagFollow{}).Where("user_id = ?", userID).Pluck("tag_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при получении подписок на теги: %w", err)
+	}
+	for _, id := range ids {
+		followed[id] = true
+	}
+	return followed, nil
+}
+
+// AddTagAlias делает alias псевдонимом тега tagName. Существующий псевдоним перенаправляется на новый тег.
+func AddTagAlias(db *gorm.DB, alias, tagName string) (models.TagAlias, error) {
+	alias, err := NormalizeTagName(alias)
+	if err != nil {
+		return models.TagAlias{}, err
+	}
+	if alias == "" {
+		return models.TagAlias{}, ErrEmptyTag
+	}
+	tag, err := GetTagByName(db, tagName)
+	if err != nil {
+		return models.TagAlias{}, err
+	}
+	var count int64
+	if err := db.Model(&models.Tag{}).Where("tag_content = ?", alias).Count(&count).Error; err != nil {
+		return models.TagAlias{}, fmt.Errorf("ошибка при поиске тега: %w", err)
+	}
+	if count > 0 {
+		return models.TagAlias{}, ErrAliasIsTag
+	}
+	tagAlias := models.TagAlias{Alias: alias, TagID: tag.ID, Tag: tag}
+	err = db.Omit("Tag").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "alias"}},
+		DoUpdates: clause.AssignmentColumns([]string{"tag_id"}),
+	}).Create(&tagAlias).Error
+	if err != nil {
+		return models.TagAlias{}, fmt.Errorf("ошибка при сохранении псевдонима: %w", err)
+	}
+	return tagAlias, nil
+}
+
+// findTagForMerge ищет тег сначала по точному имени, чтобы можно было объединять теги,
+// созданные до нормализации имён (например, "Go"), затем по нормализованному имени и псевдонимам.
+func findTagForMerge(tx *gorm.DB, name string) (models.Tag, error) {
+	var tag models.Tag
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tag_content = ?", strings.TrimSpace(name)).
+		Take(&tag).Error
+	if err == nil {
+		return tag, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Tag{}, fmt.Errorf("ошибка при получении тега: %w", err)
+	}
+	return GetTagByName(tx, name)
+}
+
+// moveTag переносит статьи, подписчиков и псевдонимы тега source на тег target и удаляет source.
+// Возвращает ID статей тега source.
+func moveTag(tx *gorm.DB, sourceTag, targetTag models.Tag) ([]uint, error) {
+	var articleIDs []uint
+	err := tx.Table("article_tags").Where("tag_id = ?", sourceTag.ID).Pluck("article_id", &articleIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении статей тега: %w", err)
+	}
+	err = tx.Exec(`
+		INSERT INTO article_tags (article_id, tag_id)
+		SELECT article_id, ? FROM article_tags WHERE tag_id = ?
+		ON CONFLICT DO NOTHING`, targetTag.ID, sourceTag.ID).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при переносе статей тега: %w", err)
+	}
+	if err := tx.Exec("DELETE FROM article_tags WHERE tag_id = ?", sourceTag.ID).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при переносе статей тега: %w", err)
+	}
+	err = tx.Exec(`
+		INSERT INTO tag_follows (user_id, tag_id, created_at)
+		SELECT user_id, ?, created_at FROM tag_follows WHERE tag_id = ?
+		ON CONFLICT DO NOTHING`, targetTag.ID, sourceTag.ID).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при переносе подписок на тег: %w", err)
+	}
+	if err := tx.Where("tag_id = ?", sourceTag.ID).Delete(&models.TagFollow{}).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при переносе подписок на тег: %w", err)
+	}
+	err = tx.Model(&models.TagAlias{}).Where("tag_id = ?", sourceTag.ID).Update("tag_id", targetTag.ID).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при переносе псевдонимов тега: %w", err)
+	}
+	if err := tx.Unscoped().Delete(&sourceTag).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при удалении тега: %w", err)
+	}
+	return articleIDs, nil
+}
+
+// MergeTags переносит статьи и подписчиков тега source на тег target и удаляет source,
+// оставляя его имя псевдонимом target. Возвращает тег target и ID статей, у которых изменились теги.
+func MergeTags(db *gorm.DB, source, target string) (models.Tag, []uint, error) {
+	var (
+		targetTag  models.Tag
+		articleIDs []uint
+	)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		sourceTag, err := findTagForMerge(tx, source)
+		if err != nil {
+			return err
+		}
+		if targetTag, err = findTagForMerge(tx, target); err != nil {
+			return err
+		}
+		if sourceTag.ID == targetTag.ID {
+			return ErrTagMergeSelf
+		}
+
+		if articleIDs, err = moveTag(tx, sourceTag, targetTag); err != nil {
+			return err
+		}
+
+		alias, err := NormalizeTagName(sourceTag.TagContent)
+		if err != nil || alias == "" || alias == targetTag.TagContent {
+			return nil
+		}
+		err = tx.Omit("Tag").Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "alias"}},
+			DoUpdates: clause.AssignmentColumns([]string{"tag_id"}),
+		}).Create(&models.TagAlias{Alias: alias, TagID: targetTag.ID}).Error
+		if err != nil {
+			return fmt.Errorf("ошибка при сохранении псевдонима: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Tag{}, nil, err
+	}
+	return targetTag, articleIDs, nil
+}
+
+// tagBackfillLockKey — ключ advisory-блокировки Postgres для BackfillTagNames.
+const tagBackfillLockKey = 7_106_401_001
+
+// BackfillTagNames приводит к канонической форме имена тегов, созданных до нормализации,
+// и объединяет теги, имена которых после нормализации совпали, например "Go" и " go".
+// Из совпавших тегов остаётся тот, что уже записан в канонической форме, иначе самый старый.
+// Всё выполняется в одной транзакции под advisory-блокировкой: реплики, запущенные одновременно,
+// проходят по очереди, и следующая видит уже нормализованные теги.
+func BackfillTagNames(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", tagBackfillLockKey).Error; err != nil {
+			return fmt.Errorf("ошибка при блокировке нормализации тегов: %w", err)
+		}
+		return backfillTagNames(tx)
+	})
+}
+
+func backfillTagNames(db *gorm.DB) error {
+	var tags []models.Tag
+	if err := db.Unscoped().Select("id, tag_content").Order("id").Find(&tags).Error; err != nil {
+		return fmt.Errorf("ошибка при получении тегов: %w", err)
+	}
+	groups := make(map[string][]models.Tag)
+	var names []string
+	for _, tag := range tags {
+		name, err := NormalizeTagName(tag.TagContent)
+		if err != nil || name == "" {
+			log.Printf("tag %d %q cannot be normalized, skipping", tag.ID, tag.TagContent)
+			continue
+		}
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], tag)
+	}
+
+	for _, name := range names {
+		group := groups[name]
+		if len(group) == 1 && group[0].TagContent == name {
+			continue
+		}
+		target := group[0]
+		for _, tag := range group {
+			if tag.TagContent == name {
+				target = tag
+				break
+			}
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, tag := range group {
+				if tag.ID == target.ID {
+					continue
+				}
+				if _, err := moveTag(tx, tag, target); err != nil {
+					return err
+				}
+			}
+			if target.TagContent == name {
+				return nil
+			}
+			if err := tx.Unscoped().Model(&target).Update("tag_content", name).Error; err != nil {
+				return fmt.Errorf("ошибка при переименовании тега: %w", err)
+			}
+			// Псевдоним с тем же именем, что и тег, больше не нужен
+			return tx.Where("alias = ?", name).Delete(&models.TagAlias{}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("ошибка при нормализации тега %q: %w", name, err)
+		}
+	}
+	return nil
+}
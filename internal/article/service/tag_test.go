@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTagName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"пустое имя", "", "", nil},
+		{"только пробелы", " \t ", "", nil},
+		{"латиница", "GoLang", "golang", nil},
+		{"кириллица", "Новости", "новости", nil},
+		{"ё", "ЁЖ", "ёж", nil},
+		{"пробелы между словами", "  машинное \t обучение ", "машинное обучение", nil},
+		{"NFD в NFC", "E\u0301te\u0301", "\u00e9t\u00e9", nil},
+		{"свёртка регистра", "STRASSE", "strasse", nil},
+		{"ß", "Straße", "strasse", nil},
+		{"предельная длина", strings.Repeat("я", maxTagLength), strings.Repeat("я", maxTagLength), nil},
+		{"слишком длинное", strings.Repeat("я", maxTagLength+1), "", ErrTagTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeTagName(tt.input)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("NormalizeTagName(%q) = %q, %v, want %q, %v", tt.input, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseTagNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"пусто", "", nil},
+		{"пустые элементы", " , ,", nil},
+		{"порядок сохраняется", "b, a", []string{"b", "a"}},
+		{"повторы после нормализации", "Go, go ,GO", []string{"go"}},
+		{"кириллица", "Наука, НАУКА, космос", []string{"наука", "космос"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTagNames(tt.input)
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("ParseTagNames(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
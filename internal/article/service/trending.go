@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"log"
+	"news/pkg/models"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	SortTrending   = "trending"
+	SortLatest     = "latest"
+	SortMostViewed = "most_viewed"
+	// SortRelevance и SortBookmarked записываются только в курсоры поиска и закладок
+	SortRelevance  = "relevance"
+	SortBookmarked = "bookmarked"
+
+	trendingKey = "articles:trending"
+	// trendingGravity задаёт скорость, с которой статья теряет рейтинг с возрастом
+	trendingGravity = 1.5
+	// trendingReactionWeight — во сколько просмотров оценивается одна реакция
+	trendingReactionWeight = 5
+	// trendingMaxAge ограничивает набор статей, для которых считается рейтинг
+	trendingMaxAge = 30 * 24 * time.Hour
+)
+
+type articleScore struct {
+	ID    uint
+	Score float64
+}
+
+// RefreshTrending пересчитывает рейтинг популярности опубликованных статей и атомарно заменяет им
+// sorted set в Redis. Рейтинг = (просмотры + weight * реакции + 1) / (часов с публикации + 2)^gravity.
+func RefreshTrending(ctx context.Context, db *gorm.DB, rdb *redis.Client) error {
+	var scores []articleScore
+	err := db.WithContext(ctx).Raw(`
+		SELECT a.id,
+			(a.num_views + ? * COALESCE(r.reactions, 0) + 1) /
+				POWER(EXTRACT(EPOCH FROM (NOW() - COALESCE(a.published_at, a.created_at))) / 3600 + 2, ?) AS score
+		FROM articles a
+		LEFT JOIN (
+			SELECT article_id, COUNT(*) AS reactions FROM article_reactions GROUP BY article_id
+		) r ON r.article_id = a.id
+		WHERE a.deleted_at IS NULL AND a.status = ? AND COALESCE(a.published_at, a.created_at) > ?`,
+		trendingReactionWeight, trendingGravity, models.ArticleStatusPublished, time.Now().Add(-trendingMaxAge),
+	).Scan(&scores).Error
+	if err != nil {
+		return err
+	}
+
+	tmpKey := trendingKey + ":tmp"
+	members := make([]redis.Z, 0, len(scores))
+	for _, s := range scores {
+		members = append(members, redis.Z{Score: s.Score, Member: strconv.FormatUint(uint64(s.ID), 10)})
+	}
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, tmpKey)
+		if len(members) == 0 {
+			pipe.Del(ctx, trendingKey)
+			return nil
+		}
+		pipe.ZAdd(ctx, tmpKey, members...)
+		pipe.Rename(ctx, tmpKey, trendingKey)
+		return nil
+	})
+	return err
+}
+
+// RunTrendingUpdater периодически пересчитывает рейтинг популярности, пока не будет отменён ctx.
+func RunTrendingUpdater(ctx context.Context, db *gorm.DB, rdb *redis.Client, interval time.Duration) {
+	if err := RefreshTrending(ctx, db, rdb); err != nil {
+		log.Printf("error refreshing trending articles: %s", err)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := RefreshTrending(ctx, db, rdb); err != nil {
+				log.Printf("error refreshing trending articles: %s", err)
+			}
+		}
+	}
+}
+
+// GetTrendingArticles возвращает страницу статей по убыванию рейтинга. Если рейтинг ещё
+// не посчитан, возвращаются последние статьи.
+func GetTrendingArticles(ctx context.Context, db *gorm.DB, rdb *redis.Client, cursor *Cursor, limit int) (ArticlePage, error) {
+	if cursor != nil && cursor.Sort == SortLatest {
+		return GetArticlesWithDetails(db, SortLatest, cursor, limit)
+	}
+	members, err := trendingMembers(ctx, rdb, cursor, limit+1)
+	if err != nil || (len(members) == 0 && cursor == nil) {
+		if err != nil {
+			log.Printf("error reading trending articles from redis: %s", err)
+		}
+		return GetArticlesWithDetails(db, SortLatest, nil, limit)
+	}
+
+	var page ArticlePage
+	if page.Total, err = rdb.ZCard(ctx, trendingKey).Result(); err != nil {
+		return ArticlePage{}, err
+	}
+	hasMore := len(members) > limit
+	if hasMore {
+		members = members[:limit]
+	}
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseUint(m.Member.(string), 10, 32)
+		if err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	if hasMore {
+		last := members[limit-1]
+		if id, err := strconv.ParseUint(last.Member.(string), 10, 32); err == nil {
+			page.NextCursor = EncodeCursor(Cursor{Sort: SortTrending, ID: uint(id), Rank: last.Score})
+		}
+	}
+	if page.Articles, err = GetArticlesByIDs(db, ids); err != nil {
+		return ArticlePage{}, err
+	}
+	return page, nil
+}
+
+// trendingMembers читает из рейтинга до count статей после курсора. Redis отдаёт статьи с равным
+// рейтингом в обратном лексикографическом порядке ID, поэтому позиция курсора — пара (рейтинг, ID):
+// сначала дочитываются статьи с тем же рейтингом, что и у последней статьи страницы, затем с меньшим.
+func trendingMembers(ctx context.Context, rdb *redis.Client, cursor *Cursor, count int) ([]redis.Z, error) {
+	if cursor == nil {
+		return rdb.ZRevRangeByScoreWithScores(ctx, trendingKey, &redis.ZRangeBy{
+			Min:   "-inf",
+			Max:   "+inf",
+			Count: int64(count),
+		}).Result()
+	}
+	score := strconv.FormatFloat(cursor.Rank, 'g', -1, 64)
+	tied, err := rdb.ZRevRangeByScoreWithScores(ctx, trendingKey, &redis.ZRangeBy{Min: score, Max: score}).Result()
+	if err != nil {
+		return nil, err
+	}
+	members := tiedAfterCursor(tied, cursor.ID, count)
+	if len(members) == count {
+		return members, nil
+	}
+	rest, err := rdb.ZRevRangeByScoreWithScores(ctx, trendingKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   "(" + score,
+		Count: int64(count - len(members)),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	return append(members, rest...), nil
+}
+
+// tiedAfterCursor оставляет из статей с рейтингом курсора до count статей, которые идут после
+// статьи курсора. Redis сравнивает ID как строки, поэтому и здесь они сравниваются как строки.
+func tiedAfterCursor(tied []redis.Z, lastID uint, count int) []redis.Z {
+	last := strconv.FormatUint(uint64(lastID), 10)
+	var members []redis.Z
+	for _, m := range tied {
+		if m.Member.(string) < last && len(members) < count {
+			members = append(members, m)
+		}
+	}
+	return members
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestTiedAfterCursor(t *testing.T) {
+	// Так Redis отдаёт статьи с равным рейтингом: по убыванию ID как строк
+	tied := []redis.Z{
+		{Score: 2, Member: "9"},
+		{Score: 2, Member: "42"},
+		{Score: 2, Member: "40"},
+		{Score: 2, Member: "100"},
+		{Score: 2, Member: "1"},
+	}
+	tests := []struct {
+		name   string
+		lastID uint
+		count  int
+		want   []string
+	}{
+		{"после первой", 9, 10, []string{"42", "40", "100", "1"}},
+		{"после средней", 40, 10, []string{"100", "1"}},
+		{"ограничение count", 9, 2, []string{"42", "40"}},
+		{"строковое сравнение", 100, 10, []string{"1"}},
+		{"после последней", 1, 10, nil},
+		{"ID вне набора", 5, 10, []string{"42", "40", "100", "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, m := range tiedAfterCursor(tied, tt.lastID, tt.count) {
+				got = append(got, m.Member.(string))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("tiedAfterCursor(%d, %d) = %v, want %v", tt.lastID, tt.count, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"news/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	pendingViewsKey = "article:views:pending"
+	// flushingViewsKey — общий для всех реплик хеш переносимых в БД просмотров, а flushBatchKey — ID этой пачки.
+	// Если перенос не удался или процесс перезапустился, пачку дозапишет следующий тик любой реплики.
+	flushingViewsKey = "article:views:flushing"
+	flushBatchKey    = "article:views:flushing:batch"
+	// viewBatchRetention — сколько хранятся ID перенесённых пачек для защиты от повторного переноса
+	viewBatchRetention = 24 * time.Hour
+)
+
+// claimViewsScript забирает накопленные просмотры в пачку и возвращает её ID. Если предыдущая пачка
+// ещё не удалена, возвращается она. Скрипт выполняется атомарно, поэтому реплики не разделят одну пачку надвое.
+var claimViewsScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[2]) == 0 then
+	if redis.call('EXISTS', KEYS[1]) == 0 then
+		return false
+	end
+	redis.call('RENAME', KEYS[1], KEYS[2])
+	redis.call('SET', KEYS[3], ARGV[1])
+elseif redis.call('EXISTS', KEYS[3]) == 0 then
+	redis.call('SET', KEYS[3], ARGV[1])
+end
+return redis.call('GET', KEYS[3])
+`)
+
+// releaseViewsScript удаляет перенесённую пачку, только если это всё ещё она, а не уже следующая.
+var releaseViewsScript = redis.NewScript(`
+if redis.call('GET', KEYS[2]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1], KEYS[2])
+end
+return 0
+`)
+
+func newViewBatchID() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
+}
+
+func viewDedupKey(articleID uint64, viewer string) string {
+	return fmt.Sprintf("article:views:seen:%d:%s", articleID, viewer)
+}
+
+// RecordView засчитывает просмотр статьи, если этот зритель не смотрел её в течение window.
+func RecordView(ctx context.Context, rdb *redis.Client, articleID uint64, viewer string, window time.Duration) (bool, error) {
+	firstView, err := rdb.SetNX(ctx, viewDedupKey(articleID, viewer), 1, window).Result()
+	if err != nil || !firstView {
+		return false, err
+	}
+	if err := rdb.HIncrBy(ctx, pendingViewsKey, strconv.FormatUint(articleID, 10), 1).Err(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PendingViews возвращает просмотры статьи, ещё не сброшенные в articles.num_views ни одной репликой.
+func PendingViews(ctx context.Context, rdb *redis.Client, articleID uint64) int {
+	id := strconv.FormatUint(articleID, 10)
+	var total int
+	for _, key := range []string{pendingViewsKey, flushingViewsKey} {
+		n, err := rdb.HGet(ctx, key, id).Int()
+		if err == nil {
+			total += n
+		}
+	}
+	return total
+}
+
+// FlushViews переносит накопленные в Redis счётчики в articles.num_views одним запросом
+// и возвращает ID обновлённых статей. Пачка отмечается в view_flush_batches в той же транзакции,
+// поэтому если удалить её из Redis не удалось, при следующем тике просмотры не засчитаются второй раз.
+func FlushViews(ctx context.Context, db *gorm.DB, rdb *redis.Client) ([]uint, error) {
+	newBatch, err := newViewBatchID()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при создании ID пачки просмотров: %w", err)
+	}
+	batch, err := claimViewsScript.Run(ctx, rdb, []string{pendingViewsKey, flushingViewsKey, flushBatchKey}, newBatch).Text()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	counters, err := rdb.HGetAll(ctx, flushingViewsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		values []string
+		args   []interface{}
+		ids    []uint
+	)
+	for idStr, countStr := range counters {
+		id, errID := strconv.ParseUint(idStr, 10, 32)
+		count, errCount := strconv.Atoi(countStr)
+		if errID != nil || errCount != nil || count <= 0 {
+			continue
+		}
+		values = append(values, "(?::bigint, ?::integer)")
+		args = append(args, id, count)
+		ids = append(ids, uint(id))
+	}
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ViewFlushBatch{ID: batch})
+		if res.Error != nil {
+			return res.Error
+		}
+		// Пачка уже перенесена, но не была удалена из Redis
+		if res.RowsAffected == 0 || len(values) == 0 {
+			return nil
+		}
+		err := tx.Exec(`
+			UPDATE articles SET num_views = articles.num_views + v.views
+			FROM (VALUES `+strings.Join(values, ", ")+`) AS v(id, views)
+			WHERE articles.id = v.id`, args...).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("created_at < ?", time.Now().Add(-viewBatchRetention)).Delete(&models.ViewFlushBatch{}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при сохранении просмотров: %w", err)
+	}
+	if err := releaseViewsScript.Run(ctx, rdb, []string{flushingViewsKey, flushBatchKey}, batch).Err(); err != nil {
+		return ids, err
+	}
+	return ids, nil
+}
+
+// RunViewFlusher периодически сбрасывает счётчики просмотров в БД, пока не будет отменён ctx.
+func RunViewFlusher(ctx context.Context, db *gorm.DB, rdb *redis.Client, interval time.Duration, onFlushed func(ctx context.Context, articleIDs []uint)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ids, err := FlushViews(ctx, db, rdb)
+			if err != nil {
+				log.Printf("error flushing article views: %s", err)
+				continue
+			}
+			if len(ids) > 0 && onFlushed != nil {
+				onFlushed(ctx, ids)
+			}
+		}
+	}
+}
@@ -1,12 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"news/internal/auth/service"
 	"news/pkg/database"
 	"news/pkg/jwt"
+	"news/pkg/middleware"
 	"news/pkg/models"
-	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/redis/go-redis/v9"
@@ -14,9 +16,15 @@ import (
 
 var redisClient *redis.Client
 
+func SetRedisClient(client *redis.Client) {
+	redisClient = client
+}
+
 type AuthRequest struct {
 	Username string `form:"username" validate:"required, min=3"`
 	Password string `form:"password" validate:"required, min=6"`
+	// Email необязателен и нужен только для восстановления пароля
+	Email string `form:"email"`
 }
 
 func Login(c echo.Context) error {
@@ -37,19 +45,7 @@ func Login(c echo.Context) error {
 	}
 	log.Println("username:", user.Username)
 
-	token, err := jwt.GenerateToken(user.ID, user.Username)
-	if err != nil {
-		log.Printf("error generating token: %s", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not generate token"})
-	}
-	cookie := new(http.Cookie)
-	cookie.Name = "jwt"
-	cookie.Value = token
-	cookie.Expires = time.Now().Add(24 * time.Hour)
-	cookie.Path = "/"
-	cookie.HttpOnly = true
-	c.SetCookie(cookie)
-	return c.Redirect(http.StatusSeeOther, "/")
+	return startSession(c, user)
 }
 
 func Register(c echo.Context) error {
@@ -62,8 +58,21 @@ func Register(c echo.Context) error {
 	if err := database.DB.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
 		return c.JSON(http.StatusConflict, map[string]string{"error": "Username aldery exist"})
 	}
+	email, err := service.NormalizeEmail(req.Email)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	taken, err := service.EmailTaken(database.DB, email, 0)
+	if err != nil {
+		log.Printf("error checking email: %s", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not create user"})
+	}
+	if taken {
+		return c.JSON(http.StatusConflict, map[string]string{"error": service.ErrEmailTaken.Error()})
+	}
 	user := models.User{
 		Username: req.Username,
+		Email:    email,
 	}
 	if err := user.HashPassword(req.Password); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not hash password"})
@@ -71,27 +80,56 @@ func Register(c echo.Context) error {
 	if err := database.DB.Create(&user).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not create user"})
 	}
-	token, err := jwt.GenerateToken(user.ID, user.Username)
-	if err != nil {
-		log.Printf("error generating token: %s", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not generate token"})
-	}
-	cookie := new(http.Cookie)
-	cookie.Name = "jwt"
-	cookie.Value = token
-	cookie.Expires = time.Now().Add(24 * time.Hour)
-	cookie.Path = "/"
-	cookie.HttpOnly = true
-	c.SetCookie(cookie)
-	return c.Redirect(http.StatusSeeOther, "/")
+	return startSession(c, user)
 }
 
+// Logout удаляет cookie с токенами, отзывает текущий токен и семейство refresh-токенов этого входа.
 func Logout(c echo.Context) error {
-	cookie := new(http.Cookie)
-	cookie.Name = "jwt"
-	cookie.Value = ""
-	cookie.Expires = time.Now().Add(-time.Hour)
-	cookie.Path = "/"
-	c.SetCookie(cookie)
+	tokenString, tokenErr := middleware.TokenFromRequest(c)
+	refreshToken := refreshTokenFromRequest(c)
+	clearTokenCookies(c)
+	ctx := c.Request().Context()
+	if refreshToken != "" {
+		familyID, err := service.FindTokenFamily(database.DB, refreshToken)
+		if err == nil {
+			err = revokeFamily(ctx, familyID)
+		}
+		if err != nil && !errors.Is(err, service.ErrInvalidRefreshToken) {
+			log.Printf("error revoking refresh tokens: %s", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not revoke token"})
+		}
+	}
+	if tokenErr == nil && redisClient != nil {
+		if claims, err := jwt.ValidateToken(tokenString); err == nil {
+			if err := jwt.RevokeToken(ctx, redisClient, claims); err != nil {
+				log.Printf("error revoking token of user %d: %s", claims.UserID, err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not revoke token"})
+			}
+		}
+	}
 	return c.JSON(http.StatusOK, map[string]string{"message": "Logged out successfully"})
 }
+
+// LogoutAll отзывает все refresh-токены пользователя и выданные ему access-токены на любых устройствах.
+func LogoutAll(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+	if err := service.RevokeUserRefreshTokens(database.DB, userID); err != nil {
+		log.Printf("error revoking refresh tokens of user %d: %s", userID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not log out"})
+	}
+	// Без Redis уже выданные access-токены доживут до истечения, но продлить их будет нельзя
+	if redisClient != nil {
+		if err := jwt.RevokeUserTokens(c.Request().Context(), redisClient, userID); err != nil {
+			log.Printf("error revoking tokens of user %d: %s", userID, err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not log out"})
+		}
+	}
+	clearTokenCookies(c)
+	if isFormPost(c) {
+		return c.Redirect(http.StatusSeeOther, "/login-page")
+	}
+	return c.JSON(http.StatusOK, map[string]string{"message": "Logged out on all devices"})
+}
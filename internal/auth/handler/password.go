@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"news/internal/auth/service"
+	"news/pkg/database"
+	"news/pkg/jwt"
+	"news/pkg/mailer"
+	"news/pkg/middleware"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var mailSender mailer.Mailer = mailer.LogMailer{}
+
+func SetMailer(m mailer.Mailer) {
+	mailSender = m
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" form:"current_password"`
+	NewPassword     string `json:"new_password" form:"new_password"`
+}
+
+type emailRequest struct {
+	CurrentPassword string `json:"current_password" form:"current_password"`
+	Email           string `json:"email" form:"email"`
+}
+
+type forgotPasswordRequest struct {
+	Login string `json:"login" form:"login"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" form:"token"`
+	Password string `json:"password" form:"password"`
+}
+
+// accountNotices и accountErrors — сообщения страницы настроек после отправки формы. В адресе
+// передаётся только код сообщения, чтобы через ссылку нельзя было показать на странице произвольный текст.
+var (
+	accountNotices = map[string]string{
+		"password": "Пароль изменён. Остальные сеансы завершены.",
+		"email":    "Адрес электронной почты сохранён.",
+	}
+	accountErrors = map[string]error{
+		"wrong_password": service.ErrWrongPassword,
+		"weak_password":  service.ErrWeakPassword,
+		"invalid_email":  service.ErrInvalidEmail,
+		"email_taken":    service.ErrEmailTaken,
+	}
+)
+
+// siteURL — публичный адрес сайта для ссылок в письмах. Он берётся только из настроек:
+// адрес из заголовка Host позволил бы подменить ссылку восстановления пароля и перехватить токен.
+var siteURL string
+
+func SetSiteURL(url string) {
+	siteURL = url
+}
+
+// accountRedirect возвращает HTML-форму на страницу настроек с сообщением об успехе или ошибке.
+func accountRedirect(c echo.Context, notice string, formErr error) error {
+	query := url.Values{}
+	if formErr != nil {
+		for code, err := range accountErrors {
+			if errors.Is(formErr, err) {
+				query.Set("error", code)
+			}
+		}
+	} else {
+		query.Set("notice", notice)
+	}
+	return c.Redirect(http.StatusSeeOther, "/account?"+query.Encode())
+}
+
+// ChangePassword меняет пароль после проверки текущего и завершает остальные сессии пользователя.
+func ChangePassword(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+	var req changePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	err = service.ChangePassword(database.DB, userID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		var status int
+		switch {
+		case errors.Is(err, service.ErrWrongPassword):
+			status = http.StatusForbidden
+		case errors.Is(err, service.ErrWeakPassword):
+			status = http.StatusBadRequest
+		default:
+			log.Printf("error changing password of user %d: %s", userID, err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not change password"})
+		}
+		if isFormPost(c) {
+			return accountRedirect(c, "", err)
+		}
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+
+	families, err := service.RevokeOtherSessions(database.DB, userID, currentFamilyID(c))
+	if err != nil {
+		log.Printf("error revoking sessions of user %d: %s", userID, err)
+	}
+	revokeAccessTokens(c.Request().Context(), families)
+	if isFormPost(c) {
+		return accountRedirect(c, "password", nil)
+	}
+	return c.JSON(http.StatusOK, map[string]string{"message": "Password changed"})
+}
+
+// revokeAccessTokens отзывает уже выданные access-токены завершённых сессий.
+func revokeAccessTokens(ctx context.Context, families []string) {
+	if redisClient == nil {
+		return
+	}
+	for _, familyID := range families {
+		if err := jwt.RevokeTokenFamily(ctx, redisClient, familyID); err != nil {
+			log.Printf("error revoking token family %s: %s", familyID, err)
+		}
+	}
+}
+
+// UpdateEmail меняет адрес, на который приходят письма для восстановления пароля. Требует текущий пароль.
+func UpdateEmail(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+	var req emailRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if err := service.UpdateEmail(database.DB, userID, req.CurrentPassword, req.Email); err != nil {
+		var status int
+		switch {
+		case errors.Is(err, service.ErrWrongPassword):
+			status = http.StatusForbidden
+		case errors.Is(err, service.ErrInvalidEmail):
+			status = http.StatusBadRequest
+		case errors.Is(err, service.ErrEmailTaken):
+			status = http.StatusConflict
+		default:
+			log.Printf("error updating email of user %d: %s", userID, err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not update email"})
+		}
+		if isFormPost(c) {
+			return accountRedirect(c, "", err)
+		}
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+	if isFormPost(c) {
+		return accountRedirect(c, "email", nil)
+	}
+	return c.JSON(http.StatusOK, map[string]string{"message": "Email updated"})
+}
+
+// Ограничения на запросы восстановления пароля за passwordResetWindow: с одного адреса
+// и для одного аккаунта, чтобы форму нельзя было использовать для рассылки писем.
+const (
+	passwordResetWindow   = time.Hour
+	passwordResetPerIP    = 10
+	passwordResetPerLogin = 3
+)
+
+// passwordResetAllowed учитывает запрос восстановления пароля в счётчиках Redis и проверяет лимиты.
+// Счётчик аккаунта растёт, даже если такого пользователя нет, чтобы по ответу нельзя было это узнать.
+// Без Redis ограничение не действует.
+func passwordResetAllowed(ctx context.Context, ip, login string) (bool, error) {
+	if redisClient == nil {
+		return true, nil
+	}
+	limits := map[string]int64{
+		"password_reset:ip:" + ip:                        passwordResetPerIP,
+		"password_reset:login:" + strings.ToLower(login): passwordResetPerLogin,
+	}
+	allowed := true
+	for key, limit := range limits {
+		count, err := redisClient.Incr(ctx, key).Result()
+		if err != nil {
+			return false, err
+		}
+		if count == 1 {
+			if err := redisClient.Expire(ctx, key, passwordResetWindow).Err(); err != nil {
+				return false, err
+			}
+		}
+		if count > limit {
+			allowed = false
+		}
+	}
+	return allowed, nil
+}
+
+// ForgotPasswordPage показывает форму запроса ссылки для восстановления пароля.
+func ForgotPasswordPage(c echo.Context) error {
+	return c.Render(http.StatusOK, "forgotpassword.html", map[string]interface{}{
+		"sent": c.QueryParam("sent") != "",
+	})
+}
+
+// ForgotPassword отправляет ссылку для восстановления пароля на почту пользователя.
+// Ответ не зависит от того, найден ли пользователь, чтобы по нему нельзя было проверять, кто зарегистрирован.
+// Частые запросы с одного адреса или для одного аккаунта отклоняются с 429.
+func ForgotPassword(c echo.Context) error {
+	var req forgotPasswordRequest
+	if err := c.Bind(&req); err != nil || strings.TrimSpace(req.Login) == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	allowed, err := passwordResetAllowed(c.Request().Context(), c.RealIP(), strings.TrimSpace(req.Login))
+	if err != nil {
+		log.Printf("error checking password reset rate limit: %s", err)
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Could not reset password"})
+	}
+	if !allowed {
+		if isFormPost(c) {
+			return c.Render(http.StatusTooManyRequests, "forgotpassword.html", map[string]interface{}{
+				"error": "Слишком много запросов на восстановление пароля. Попробуйте позже.",
+			})
+		}
+		return c.JSON(http.StatusTooManyRequests, map[string]string{"error": "Too many password reset requests"})
+	}
+	user, token, err := service.RequestPasswordReset(database.DB, req.Login)
+	switch {
+	case err == nil:
+		link := siteURL + "/reset-password?token=" + url.QueryEscape(token)
+		msg := mailer.Message{
+			To:      *user.Email,
+			Subject: "Восстановление пароля",
+			Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s\n\n"+
+				"Ссылка действует %d мин. и может быть использована один раз. "+
+				"Если вы не запрашивали восстановление пароля, просто проигнорируйте это письмо.\n",
+				user.Username, link, int(service.PasswordResetLifetime.Minutes())),
+		}
+		ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+		defer cancel()
+		if err := mailSender.Send(ctx, msg); err != nil {
+			log.Printf("error sending password reset mail to user %d: %s", user.ID, err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not send email"})
+		}
+	case errors.Is(err, service.ErrUserNotFound):
+	default:
+		log.Printf("error requesting password reset: %s", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not reset password"})
+	}
+	if isFormPost(c) {
+		return c.Redirect(http.StatusSeeOther, "/forgot-password?sent=1")
+	}
+	return c.JSON(http.StatusOK, map[string]string{"message": "If the account has an email, a reset link has been sent"})
+}
+
+// ResetPasswordPage показывает форму нового пароля по ссылке из письма.
+func ResetPasswordPage(c echo.Context) error {
+	token := c.QueryParam("token")
+	data := map[string]interface{}{"token": token}
+	if err := service.CheckPasswordResetToken(database.DB, token); err != nil {
+		if !errors.Is(err, service.ErrInvalidResetToken) {
+			log.Printf("error checking password reset token: %s", err)
+		}
+		data["error"] = service.ErrInvalidResetToken.Error()
+		data["invalid"] = true
+	}
+	return c.Render(http.StatusOK, "resetpassword.html", data)
+}
+
+// ResetPassword задаёт новый пароль по токену из письма и завершает все сессии пользователя.
+func ResetPassword(c echo.Context) error {
+	var req resetPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	userID, err := service.ResetPassword(database.DB, req.Token, req.Password)
+	if err != nil {
+		var status int
+		switch {
+		case errors.Is(err, service.ErrInvalidResetToken):
+			status = http.StatusBadRequest
+		case errors.Is(err, service.ErrWeakPassword):
+			status = http.StatusBadRequest
+		default:
+			log.Printf("error resetting password: %s", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not reset password"})
+		}
+		if isFormPost(c) {
+			return c.Render(status, "resetpassword.html", map[string]interface{}{
+				"token":   req.Token,
+				"error":   err.Error(),
+				"invalid": errors.Is(err, service.ErrInvalidResetToken),
+			})
+		}
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+
+	if redisClient != nil {
+		if err := jwt.RevokeUserTokens(c.Request().Context(), redisClient, userID); err != nil {
+			log.Printf("error revoking tokens of user %d: %s", userID, err)
+		}
+	}
+	clearTokenCookies(c)
+	if isFormPost(c) {
+		return c.Redirect(http.StatusSeeOther, "/login-page")
+	}
+	return c.JSON(http.StatusOK, map[string]string{"message": "Password has been reset"})
+}
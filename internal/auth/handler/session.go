@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"news/internal/auth/service"
+	"news/pkg/database"
+	"news/pkg/jwt"
+	"news/pkg/middleware"
+	"news/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SessionView — сессия в списке активных входов пользователя.
+type SessionView struct {
+	ID         uint      `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// Current отмечает сессию, из которой сделан запрос
+	Current bool `json:"current"`
+}
+
+func isFormPost(c echo.Context) bool {
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	return strings.HasPrefix(contentType, echo.MIMEApplicationForm) || strings.HasPrefix(contentType, echo.MIMEMultipartForm)
+}
+
+func sessionClient(c echo.Context) service.SessionClient {
+	return service.SessionClient{
+		UserAgent: c.Request().UserAgent(),
+		IP:        c.RealIP(),
+	}
+}
+
+// currentFamilyID возвращает семейство токенов текущего запроса, то есть его сессию.
+func currentFamilyID(c echo.Context) string {
+	tokenString, err := middleware.TokenFromRequest(c)
+	if err != nil {
+		return ""
+	}
+	claims, err := jwt.ValidateToken(tokenString)
+	if err != nil {
+		return ""
+	}
+	return claims.FamilyID
+}
+
+// describeUserAgent превращает строку User-Agent в короткое название браузера и системы.
+func describeUserAgent(userAgent string) string {
+	browser := ""
+	for _, b := range []struct{ marker, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"YaBrowser/", "Яндекс Браузер"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(userAgent, b.marker) {
+			browser = b.name
+			break
+		}
+	}
+	system := ""
+	for _, s := range []struct{ marker, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, s.marker) {
+			system = s.name
+			break
+		}
+	}
+	switch {
+	case browser != "" && system != "":
+		return browser + ", " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	case userAgent != "":
+		return userAgent
+	}
+	return "Неизвестное устройство"
+}
+
+func newSessionViews(sessions []models.Session, currentFamily string) []SessionView {
+	views := make([]SessionView, 0, len(sessions))
+	for _, session := range sessions {
+		views = append(views, SessionView{
+			ID:         session.ID,
+			Device:     describeUserAgent(session.UserAgent),
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    currentFamily != "" && session.FamilyID == currentFamily,
+		})
+	}
+	return views
+}
+
+// ListSessions возвращает активные сессии пользователя.
+func ListSessions(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+	sessions, err := service.ListSessions(database.DB, userID)
+	if err != nil {
+		log.Printf("error listing sessions of user %d: %s", userID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not load sessions"})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"sessions": newSessionViews(sessions, currentFamilyID(c))})
+}
+
+// AccountPage показывает страницу настроек аккаунта: активные сессии, смену пароля и адрес почты.
+func AccountPage(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.Redirect(http.StatusSeeOther, "/login-page")
+	}
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		log.Printf("error getting user %d: %s", userID, err)
+		return c.Redirect(http.StatusSeeOther, "/login-page")
+	}
+	sessions, err := service.ListSessions(database.DB, userID)
+	if err != nil {
+		log.Printf("error listing sessions of user %d: %s", userID, err)
+		return c.String(http.StatusInternalServerError, "Не удалось загрузить сессии")
+	}
+	data := map[string]interface{}{
+		"username": user.Username,
+		"email":    "",
+		"sessions": newSessionViews(sessions, currentFamilyID(c)),
+		"notice":   accountNotices[c.QueryParam("notice")],
+		"csrf":     c.Get(middleware.CSRFContextKey),
+	}
+	if user.Email != nil {
+		data["email"] = *user.Email
+	}
+	if err, ok := accountErrors[c.QueryParam("error")]; ok {
+		data["error"] = err.Error()
+	}
+	return c.Render(http.StatusOK, "account.html", data)
+}
+
+// RevokeSession завершает сессию пользователя на другом устройстве или текущую.
+// Уже выданные в ней access-токены отзываются вместе с refresh-токенами.
+func RevokeSession(c echo.Context) error {
+	userID, err := middleware.GetUserIDFromToken(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+	}
+	sessionID, err := strconv.ParseUint(c.Param("session_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid session ID"})
+	}
+	currentFamily := currentFamilyID(c)
+	familyID, err := service.RevokeSession(database.DB, userID, sessionID)
+	if err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Session not found"})
+		}
+		log.Printf("error revoking session %d of user %d: %s", sessionID, userID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not revoke session"})
+	}
+	if redisClient != nil {
+		if err := jwt.RevokeTokenFamily(c.Request().Context(), redisClient, familyID); err != nil {
+			log.Printf("error revoking token family %s: %s", familyID, err)
+		}
+	}
+	current := familyID == currentFamily
+	if current {
+		clearTokenCookies(c)
+	}
+	if isFormPost(c) {
+		if current {
+			return c.Redirect(http.StatusSeeOther, "/login-page")
+		}
+		return c.Redirect(http.StatusSeeOther, "/account")
+	}
+	return c.JSON(http.StatusOK, map[string]string{"message": "Session revoked"})
+}
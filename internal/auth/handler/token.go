@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"news/internal/auth/service"
+	"news/pkg/database"
+	"news/pkg/jwt"
+	"news/pkg/middleware"
+	"news/pkg/models"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TokenResponse — пара токенов для клиентов, которые не используют cookie.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" form:"refresh_token"`
+}
+
+func wantsJSON(c echo.Context) bool {
+	accept := c.Request().Header.Get(echo.HeaderAccept)
+	return strings.Contains(accept, echo.MIMEApplicationJSON) && !strings.Contains(accept, echo.MIMETextHTML)
+}
+
+func newTokenResponse(accessToken, refreshToken string) TokenResponse {
+	return TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(jwt.TokenLifetime.Seconds()),
+	}
+}
+
+// startSession начинает новое семейство refresh-токенов после входа или регистрации
+// и выдаёт токены в cookie, а клиентам JSON API — ещё и в теле ответа.
+func startSession(c echo.Context, user models.User) error {
+	familyID, refreshToken, err := service.StartTokenFamily(database.DB, user.ID, sessionClient(c))
+	if err != nil {
+		log.Printf("error starting token family: %s", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not generate token"})
+	}
+	accessToken, err := jwt.GenerateToken(user.ID, user.Username, familyID)
+	if err != nil {
+		log.Printf("error generating token: %s", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not generate token"})
+	}
+	setTokenCookies(c, accessToken, refreshToken)
+	if wantsJSON(c) {
+		return c.JSON(http.StatusOK, newTokenResponse(accessToken, refreshToken))
+	}
+	return c.Redirect(http.StatusSeeOther, "/")
+}
+
+func setTokenCookies(c echo.Context, accessToken, refreshToken string) {
+	cookie := new(http.Cookie)
+	cookie.Name = "jwt"
+	cookie.Value = accessToken
+	cookie.Expires = time.Now().Add(jwt.TokenLifetime)
+	cookie.Path = "/"
+	cookie.HttpOnly = true
+	// Lax: браузер не пришлёт токен с POST-формы чужого сайта
+	cookie.SameSite = http.SameSiteLaxMode
+	c.SetCookie(cookie)
+
+	refresh := new(http.Cookie)
+	refresh.Name = middleware.RefreshCookie
+	refresh.Value = refreshToken
+	refresh.Expires = time.Now().Add(service.RefreshTokenLifetime)
+	refresh.Path = "/"
+	refresh.HttpOnly = true
+	refresh.SameSite = http.SameSiteLaxMode
+	c.SetCookie(refresh)
+}
+
+func clearTokenCookies(c echo.Context) {
+	for _, name := range []string{"jwt", middleware.RefreshCookie} {
+		cookie := new(http.Cookie)
+		cookie.Name = name
+		cookie.Value = ""
+		cookie.Expires = time.Now().Add(-time.Hour)
+		cookie.Path = "/"
+		c.SetCookie(cookie)
+	}
+}
+
+func refreshTokenFromRequest(c echo.Context) string {
+	if cookie, err := c.Cookie(middleware.RefreshCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// revokeFamily отзывает refresh-токены семейства и выданные по ним access-токены.
+func revokeFamily(ctx context.Context, familyID string) error {
+	if err := service.RevokeTokenFamily(database.DB, familyID); err != nil {
+		return err
+	}
+	if redisClient != nil {
+		return jwt.RevokeTokenFamily(ctx, redisClient, familyID)
+	}
+	return nil
+}
+
+// safeRedirectTarget пропускает только относительные адреса этого сайта, чтобы ?next= нельзя было
+// использовать для перенаправления на чужой домен.
+func safeRedirectTarget(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// RefreshToken обменивает refresh-токен на новую пару токенов. Токен берётся из тела запроса
+// или из cookie. GET-запрос приходит от JWTAuth, когда access-токен в браузере истёк,
+// и после обмена перенаправляет обратно на страницу из параметра next.
+func RefreshToken(c echo.Context) error {
+	browser := c.Request().Method == http.MethodGet
+	token := refreshTokenFromRequest(c)
+	fromCookie := token != ""
+	if !browser {
+		var req refreshRequest
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+		}
+		if req.RefreshToken != "" {
+			token = req.RefreshToken
+			fromCookie = false
+		}
+	}
+	fail := func(status int, message string) error {
+		if fromCookie {
+			clearTokenCookies(c)
+		}
+		if browser {
+			return c.Redirect(http.StatusSeeOther, "/login-page")
+		}
+		return c.JSON(status, map[string]string{"error": message})
+	}
+	if token == "" {
+		return fail(http.StatusUnauthorized, "Refresh token required")
+	}
+
+	rotated, err := service.RotateRefreshToken(database.DB, token, sessionClient(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRefreshToken):
+			return fail(http.StatusUnauthorized, "Invalid refresh token")
+		case errors.Is(err, service.ErrRefreshTokenReused):
+			log.Printf("refresh token reuse detected for user %d, family %s revoked", rotated.User.ID, rotated.FamilyID)
+			if redisClient != nil {
+				if err := jwt.RevokeTokenFamily(c.Request().Context(), redisClient, rotated.FamilyID); err != nil {
+					log.Printf("error revoking token family %s: %s", rotated.FamilyID, err)
+				}
+			}
+			return fail(http.StatusUnauthorized, "Refresh token reused, session revoked")
+		default:
+			log.Printf("error rotating refresh token: %s", err)
+			return fail(http.StatusInternalServerError, "Could not refresh token")
+		}
+	}
+
+	accessToken, err := jwt.GenerateToken(rotated.User.ID, rotated.User.Username, rotated.FamilyID)
+	if err != nil {
+		log.Printf("error generating token: %s", err)
+		return fail(http.StatusInternalServerError, "Could not generate token")
+	}
+	if fromCookie {
+		setTokenCookies(c, accessToken, rotated.RefreshToken)
+	}
+	if browser {
+		return c.Redirect(http.StatusSeeOther, safeRedirectTarget(c.QueryParam("next")))
+	}
+	return c.JSON(http.StatusOK, newTokenResponse(accessToken, rotated.RefreshToken))
+}
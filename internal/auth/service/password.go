@@ -0,0 +1,210 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"news/pkg/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// MinPasswordLength — минимальная длина пароля.
+	MinPasswordLength = 6
+	// PasswordResetLifetime — срок действия ссылки для восстановления пароля.
+	PasswordResetLifetime = time.Hour
+)
+
+var (
+	ErrWrongPassword     = errors.New("неверный текущий пароль")
+	ErrWeakPassword      = fmt.Errorf("пароль должен быть не короче %d символов", MinPasswordLength)
+	ErrInvalidResetToken = errors.New("ссылка для восстановления пароля недействительна или устарела")
+	ErrInvalidEmail      = errors.New("неверный адрес электронной почты")
+	ErrEmailTaken        = errors.New("адрес электронной почты уже используется")
+	ErrUserNotFound      = errors.New("пользователь не найден")
+)
+
+func setPassword(tx *gorm.DB, user *models.User, password string) error {
+	if len([]rune(password)) < MinPasswordLength {
+		return ErrWeakPassword
+	}
+	if err := user.HashPassword(password); err != nil {
+		return fmt.Errorf("ошибка при хешировании пароля: %w", err)
+	}
+	if err := tx.Model(user).Update("password_hash", user.PasswordHash).Error; err != nil {
+		return fmt.Errorf("ошибка при сохранении пароля: %w", err)
+	}
+	return nil
+}
+
+// ChangePassword меняет пароль пользователя после проверки текущего пароля.
+func ChangePassword(db *gorm.DB, userID uint, currentPassword, newPassword string) error {
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if err := user.CheckPassword(currentPassword); err != nil {
+		return ErrWrongPassword
+	}
+	return setPassword(db, &user, newPassword)
+}
+
+// NormalizeEmail проверяет адрес и приводит его к нижнему регистру. Пустой адрес означает nil — почта не указана.
+func NormalizeEmail(email string) (*string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 255 {
+		return nil, ErrInvalidEmail
+	}
+	email = strings.ToLower(email)
+	return &email, nil
+}
+
+// EmailTaken проверяет, привязан ли адрес к другому пользователю.
+func EmailTaken(db *gorm.DB, email *string, exceptUserID uint) (bool, error) {
+	if email == nil {
+		return false, nil
+	}
+	var count int64
+	err := db.Model(&models.User{}).Where("email = ? AND id <> ?", *email, exceptUserID).Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("ошибка при проверке адреса: %w", err)
+	}
+	return count > 0, nil
+}
+
+// UpdateEmail меняет адрес электронной почты пользователя после проверки текущего пароля:
+// иначе по украденной сессии можно было бы привязать свой адрес и восстановить через него пароль.
+// Пустой адрес отвязывает почту.
+func UpdateEmail(db *gorm.DB, userID uint, currentPassword, email string) error {
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if err := user.CheckPassword(currentPassword); err != nil {
+		return ErrWrongPassword
+	}
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		return err
+	}
+	taken, err := EmailTaken(db, normalized, userID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrEmailTaken
+	}
+	err = db.Model(&models.User{}).Where("id = ?", userID).Update("email", normalized).Error
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении адреса: %w", err)
+	}
+	return nil
+}
+
+// RequestPasswordReset создаёт токен восстановления пароля для пользователя с именем или адресом login.
+// Прежние неиспользованные токены пользователя перестают действовать. Если пользователь не найден
+// или у него нет почты, возвращается ErrUserNotFound — вызывающий не должен сообщать об этом клиенту.
+func RequestPasswordReset(db *gorm.DB, login string) (models.User, string, error) {
+	login = strings.TrimSpace(login)
+	var user models.User
+	err := db.Where("username = ? OR email = ?", login, strings.ToLower(login)).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.User{}, "", ErrUserNotFound
+		}
+		return models.User{}, "", fmt.Errorf("ошибка при получении пользователя: %w", err)
+	}
+	if user.Email == nil {
+		return models.User{}, "", ErrUserNotFound
+	}
+	token, err := randomToken(32)
+	if err != nil {
+		return models.User{}, "", fmt.Errorf("ошибка при создании токена: %w", err)
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: hashToken(token),
+			ExpiresAt: time.Now().Add(PasswordResetLifetime),
+		}).Error
+	})
+	if err != nil {
+		return models.User{}, "", fmt.Errorf("ошибка при сохранении токена: %w", err)
+	}
+	return user, token, nil
+}
+
+// CheckPasswordResetToken проверяет токен без его использования, чтобы не показывать форму по устаревшей ссылке.
+func CheckPasswordResetToken(db *gorm.DB, token string) error {
+	var count int64
+	err := db.Model(&models.PasswordResetToken{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(token), time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("ошибка при проверке токена: %w", err)
+	}
+	if count == 0 {
+		return ErrInvalidResetToken
+	}
+	return nil
+}
+
+// ResetPassword задаёт новый пароль по токену восстановления и гасит токен. Все сессии пользователя
+// завершаются: тот, кто узнал старый пароль, не должен остаться в системе.
+func ResetPassword(db *gorm.DB, token, newPassword string) (uint, error) {
+	var userID uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var record models.PasswordResetToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("User").
+			Where("token_hash = ?", hashToken(token)).
+			First(&record).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidResetToken
+			}
+			return fmt.Errorf("ошибка при получении токена: %w", err)
+		}
+		if record.UsedAt != nil || time.Now().After(record.ExpiresAt) {
+			return ErrInvalidResetToken
+		}
+		if err := setPassword(tx, &record.User, newPassword); err != nil {
+			return err
+		}
+		if err := tx.Model(&record).Update("used_at", time.Now()).Error; err != nil {
+			return fmt.Errorf("ошибка при обновлении токена: %w", err)
+		}
+		if err := revoke(tx, &models.RefreshToken{}, "user_id = ?", record.UserID); err != nil {
+			return err
+		}
+		if err := revoke(tx, &models.Session{}, "user_id = ?", record.UserID); err != nil {
+			return err
+		}
+		userID = record.UserID
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"news/pkg/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RefreshTokenLifetime — срок действия refresh-токена. Каждый обмен продлевает сессию на этот срок.
+const RefreshTokenLifetime = 30 * 24 * time.Hour
+
+// RefreshReuseGrace — сколько после обмена токен ещё можно предъявить повторно без отзыва семейства.
+// Так переживают гонку параллельных запросов из нескольких вкладок, которые одновременно обменивают один токен.
+const RefreshReuseGrace = 10 * time.Second
+
+var (
+	ErrInvalidRefreshToken = errors.New("недействительный refresh-токен")
+	// ErrRefreshTokenReused означает, что уже обменянный токен предъявлен повторно — скорее всего, он украден.
+	// Всё семейство токенов к этому моменту отозвано.
+	ErrRefreshTokenReused = errors.New("refresh-токен уже использован")
+)
+
+// RotatedToken — результат обмена refresh-токена.
+type RotatedToken struct {
+	User         models.User
+	FamilyID     string
+	RefreshToken string
+}
+
+func randomToken(size int) (string, error) {
+	random := make([]byte, size)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// successorCipher возвращает шифр для преемника токена. Ключ выводится из самого токена, а не из его
+// хеша в БД, поэтому прочитать преемника может только тот, кто предъявил исходный токен.
+func successorCipher(token string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte("refresh-successor:" + token))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealSuccessor шифрует следующий токен семейства ключом из предъявленного токена.
+func sealSuccessor(token, successor string) (string, error) {
+	aead, err := successorCipher(token)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(successor), nil)), nil
+}
+
+// openSuccessor расшифровывает следующий токен, сохранённый sealSuccessor.
+func openSuccessor(token, sealed string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	aead, err := successorCipher(token)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", ErrInvalidRefreshToken
+	}
+	successor, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(successor), nil
+}
+
+func issueRefreshToken(tx *gorm.DB, userID uint, familyID string) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("ошибка при создании refresh-токена: %w", err)
+	}
+	record := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(RefreshTokenLifetime),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return "", fmt.Errorf("ошибка при сохранении refresh-токена: %w", err)
+	}
+	return token, nil
+}
+
+// StartTokenFamily выдаёт первый refresh-токен нового семейства при входе пользователя и заводит для него сессию.
+func StartTokenFamily(db *gorm.DB, userID uint, client SessionClient) (familyID, refreshToken string, err error) {
+	familyID, err = randomToken(16)
+	if err != nil {
+		return "", "", fmt.Errorf("ошибка при создании семейства токенов: %w", err)
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		session := models.Session{
+			UserID:     userID,
+			FamilyID:   familyID,
+			UserAgent:  truncate(client.UserAgent, 255),
+			IP:         truncate(client.IP, 64),
+			LastSeenAt: time.Now(),
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return fmt.Errorf("ошибка при создании сессии: %w", err)
+		}
+		refreshToken, err = issueRefreshToken(tx, userID, familyID)
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return familyID, refreshToken, nil
+}
+
+// refreshUse — вид предъявления refresh-токена.
+type refreshUse int
+
+const (
+	// refreshInvalid — токен отозван или истёк
+	refreshInvalid refreshUse = iota
+	// refreshFirstUse — токен ещё не обменивался
+	refreshFirstUse
+	// refreshRaceReuse — повтор в пределах RefreshReuseGrace после обмена
+	refreshRaceReuse
+	// refreshReused — повтор после RefreshReuseGrace: токен, скорее всего, украден
+	refreshReused
+)
+
+// classifyRefreshUse определяет, как обработать токен record, предъявленный в момент now.
+func classifyRefreshUse(record models.RefreshToken, now time.Time) refreshUse {
+	switch {
+	case record.RevokedAt != nil || now.After(record.ExpiresAt):
+		return refreshInvalid
+	case record.UsedAt == nil:
+		return refreshFirstUse
+	case now.Sub(*record.UsedAt) > RefreshReuseGrace:
+		return refreshReused
+	default:
+		return refreshRaceReuse
+	}
+}
+
+// RotateRefreshToken обменивает refresh-токен на новый того же семейства. Предъявленный токен
+// помечается использованным. Повторное предъявление в пределах RefreshReuseGrace считается гонкой
+// параллельных запросов и получает тот же новый токен, что и первый запрос, так что семейство
+// не ветвится; более позднее отзывает всё семейство и возвращает ErrRefreshTokenReused.
+// Время, адрес и браузер последнего обмена запоминаются в сессии.
+func RotateRefreshToken(db *gorm.DB, token string, client SessionClient) (RotatedToken, error) {
+	var result RotatedToken
+	reused := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var record models.RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("User").
+			Where("token_hash = ?", hashToken(token)).
+			First(&record).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return fmt.Errorf("ошибка при получении refresh-токена: %w", err)
+		}
+		use := classifyRefreshUse(record, time.Now())
+		if use == refreshInvalid {
+			return ErrInvalidRefreshToken
+		}
+		if use == refreshReused {
+			// Транзакция должна зафиксировать отзыв, поэтому ошибка возвращается уже после неё
+			reused = true
+			result.User = record.User
+			result.FamilyID = record.FamilyID
+			return revokeFamily(tx, record.FamilyID)
+		}
+
+		var refreshToken string
+		if use == refreshRaceReuse {
+			// Строка заблокирована первым обменом, так что его преемник уже сохранён
+			refreshToken, err = openSuccessor(token, record.Successor)
+			if err != nil {
+				return ErrInvalidRefreshToken
+			}
+		} else {
+			refreshToken, err = issueRefreshToken(tx, record.UserID, record.FamilyID)
+			if err != nil {
+				return err
+			}
+			sealed, err := sealSuccessor(token, refreshToken)
+			if err != nil {
+				return fmt.Errorf("ошибка при сохранении refresh-токена: %w", err)
+			}
+			err = tx.Model(&record).Updates(map[string]interface{}{
+				"used_at":   time.Now(),
+				"successor": sealed,
+			}).Error
+			if err != nil {
+				return fmt.Errorf("ошибка при обновлении refresh-токена: %w", err)
+			}
+		}
+		err = tx.Model(&models.Session{}).Where("family_id = ?", record.FamilyID).Updates(map[string]interface{}{
+			"last_seen_at": time.Now(),
+			"user_agent":   truncate(client.UserAgent, 255),
+			"ip":           truncate(client.IP, 64),
+		}).Error
+		if err != nil {
+			return fmt.Errorf("ошибка при обновлении сессии: %w", err)
+		}
+		result = RotatedToken{User: record.User, FamilyID: record.FamilyID, RefreshToken: refreshToken}
+		return nil
+	})
+	if err != nil {
+		return RotatedToken{}, err
+	}
+	if reused {
+		return result, ErrRefreshTokenReused
+	}
+	return result, nil
+}
+
+// FindTokenFamily возвращает семейство, к которому относится refresh-токен, даже если токен уже использован или отозван.
+func FindTokenFamily(db *gorm.DB, token string) (string, error) {
+	var record models.RefreshToken
+	err := db.Select("family_id").Where("token_hash = ?", hashToken(token)).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrInvalidRefreshToken
+		}
+		return "", fmt.Errorf("ошибка при получении refresh-токена: %w", err)
+	}
+	return record.FamilyID, nil
+}
+
+// RevokeTokenFamily отзывает все refresh-токены семейства и его сессию.
+func RevokeTokenFamily(db *gorm.DB, familyID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return revokeFamily(tx, familyID)
+	})
+}
+
+// RevokeUserRefreshTokens отзывает все refresh-токены и сессии пользователя.
+func RevokeUserRefreshTokens(db *gorm.DB, userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := revoke(tx, &models.RefreshToken{}, "user_id = ?", userID); err != nil {
+			return err
+		}
+		return revoke(tx, &models.Session{}, "user_id = ?", userID)
+	})
+}
+
+func revokeFamily(tx *gorm.DB, familyID string) error {
+	if err := revoke(tx, &models.RefreshToken{}, "family_id = ?", familyID); err != nil {
+		return err
+	}
+	return revoke(tx, &models.Session{}, "family_id = ?", familyID)
+}
+
+// revoke проставляет revoked_at записям model, подходящим под условие и ещё не отозванным.
+func revoke(tx *gorm.DB, model interface{}, query string, args ...interface{}) error {
+	err := tx.Model(model).
+		Where(query, args...).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("ошибка при отзыве токенов: %w", err)
+	}
+	return nil
+}
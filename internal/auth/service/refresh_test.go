@@ -0,0 +1,81 @@
+package service
+
+import (
+	"encoding/base64"
+	"news/pkg/models"
+	"testing"
+	"time"
+)
+
+func TestClassifyRefreshUse(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+	valid := now.Add(RefreshTokenLifetime)
+	tests := []struct {
+		name   string
+		record models.RefreshToken
+		want   refreshUse
+	}{
+		{"первый обмен", models.RefreshToken{ExpiresAt: valid}, refreshFirstUse},
+		{"повтор сразу после обмена", models.RefreshToken{ExpiresAt: valid, UsedAt: at(-time.Second)}, refreshRaceReuse},
+		{"повтор на границе окна", models.RefreshToken{ExpiresAt: valid, UsedAt: at(-RefreshReuseGrace)}, refreshRaceReuse},
+		{"повтор после окна", models.RefreshToken{ExpiresAt: valid, UsedAt: at(-RefreshReuseGrace - time.Millisecond)}, refreshReused},
+		{"повтор через день", models.RefreshToken{ExpiresAt: valid, UsedAt: at(-24 * time.Hour)}, refreshReused},
+		{"отозван", models.RefreshToken{ExpiresAt: valid, RevokedAt: at(-time.Hour)}, refreshInvalid},
+		{"отозван после обмена", models.RefreshToken{ExpiresAt: valid, UsedAt: at(-time.Second), RevokedAt: at(0)}, refreshInvalid},
+		{"истёк", models.RefreshToken{ExpiresAt: now.Add(-time.Second)}, refreshInvalid},
+		{"истекает в момент предъявления", models.RefreshToken{ExpiresAt: now}, refreshFirstUse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyRefreshUse(tt.record, now); got != tt.want {
+				t.Errorf("classifyRefreshUse = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSealSuccessor(t *testing.T) {
+	const token, successor = "presented-token", "successor-token"
+	sealed, err := sealSuccessor(token, successor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := sealSuccessor(token, successor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed == again {
+		t.Error("два шифрования одного преемника совпали: nonce не случаен")
+	}
+	data, _ := base64.RawURLEncoding.DecodeString(sealed)
+	data[len(data)-1] ^= 1
+	tampered := base64.RawURLEncoding.EncodeToString(data)
+
+	tests := []struct {
+		name    string
+		token   string
+		sealed  string
+		want    string
+		wantErr bool
+	}{
+		{"тот же токен", token, sealed, successor, false},
+		{"второе шифрование", token, again, successor, false},
+		{"другой токен", "other-token", sealed, "", true},
+		{"изменённые данные", token, tampered, "", true},
+		{"пустое значение", token, "", "", true},
+		{"короче nonce", token, base64.RawURLEncoding.EncodeToString([]byte("short")), "", true},
+		{"не base64", token, "!!!", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := openSuccessor(tt.token, tt.sealed)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("openSuccessor = %q, %v, want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"news/pkg/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrSessionNotFound = errors.New("сессия не найдена")
+
+// SessionClient — сведения об устройстве, с которого выполнен вход или обмен токена.
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit])
+}
+
+// ListSessions возвращает действующие сессии пользователя, начиная с последней активной.
+// Сессия без обмена токена дольше RefreshTokenLifetime считается истёкшей.
+func ListSessions(db *gorm.DB, userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := db.Where("user_id = ? AND revoked_at IS NULL AND last_seen_at > ?", userID, time.Now().Add(-RefreshTokenLifetime)).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении сессий: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession завершает сессию пользователя и возвращает её семейство токенов,
+// чтобы вызывающий мог отозвать и уже выданные access-токены.
+func RevokeSession(db *gorm.DB, userID uint, sessionID uint64) (string, error) {
+	var session models.Session
+	err := db.Select("id, family_id").
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrSessionNotFound
+		}
+		return "", fmt.Errorf("ошибка при получении сессии: %w", err)
+	}
+	if err := RevokeTokenFamily(db, session.FamilyID); err != nil {
+		return "", err
+	}
+	return session.FamilyID, nil
+}
+
+// RevokeOtherSessions завершает все сессии пользователя, кроме keepFamilyID, и возвращает их семейства токенов.
+func RevokeOtherSessions(db *gorm.DB, userID uint, keepFamilyID string) ([]string, error) {
+	var families []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Session{}).
+			Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", userID, keepFamilyID).
+			Pluck("family_id", &families).Error
+		if err != nil {
+			return fmt.Errorf("ошибка при получении сессий: %w", err)
+		}
+		if err := revoke(tx, &models.RefreshToken{}, "user_id = ? AND family_id <> ?", userID, keepFamilyID); err != nil {
+			return err
+		}
+		return revoke(tx, &models.Session{}, "user_id = ? AND family_id <> ?", userID, keepFamilyID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return families, nil
+}
@@ -3,13 +3,21 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	JWTSecret       string
 	AuthServisePort string
+	// JWTKeysDir — каталог с закрытыми ключами подписи токенов, нужен только сервису аутентификации
+	JWTKeysDir     string
+	JWTKeyRotation string
+	// JWKSURL — адрес открытых ключей сервиса аутентификации для проверки токенов в остальных сервисах
+	JWKSURL string
+	// SiteURL — публичный адрес сайта без завершающего слеша для абсолютных ссылок в письмах, лентах и карте сайта.
+	// Заголовкам запроса доверять нельзя: Host подставляет клиент
+	SiteURL string
 }
 
 func LoadConfig() *Config {
@@ -17,8 +25,11 @@ func LoadConfig() *Config {
 		log.Printf("No .env file found")
 	}
 	return &Config{
-		JWTSecret:       GetEnv("JWT_SECRET", ""),
 		AuthServisePort: GetEnv("AUTH_SERVICE_PORT", "8081"),
+		JWTKeysDir:      GetEnv("JWT_KEYS_DIR", "./keys"),
+		JWTKeyRotation:  GetEnv("JWT_KEY_ROTATION", "720h"),
+		JWKSURL:         GetEnv("JWKS_URL", "http://auth-service:8080/.well-known/jwks.json"),
+		SiteURL:         strings.TrimRight(GetEnv("SITE_URL", ""), "/"),
 	}
 }
 
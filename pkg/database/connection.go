@@ -1,11 +1,15 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"news/pkg/models"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -13,7 +17,7 @@ import (
 
 var DB *gorm.DB
 
-//var redisClient *redis.Client
+var Redis *redis.Client
 
 func InitDB() error {
 	var err error
@@ -32,8 +36,21 @@ func InitDB() error {
 	}
 	err = DB.AutoMigrate(
 		&models.User{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.PasswordResetToken{},
 		&models.Tag{},
+		&models.TagAlias{},
 		&models.Article{},
+		&models.ArticleSlug{},
+		&models.ArticleRevision{},
+		&models.Comment{},
+		&models.ArticleReaction{},
+		&models.Bookmark{},
+		&models.Follow{},
+		&models.TagFollow{},
+		&models.Attachment{},
+		&models.ViewFlushBatch{},
 	)
 	if err != nil {
 		log.Printf("error migrate DB: %s", err)
@@ -57,6 +74,9 @@ func createIndexForDB() error {
 		`CREATE INDEX IF NOT EXISTS idx_tags_content ON tags USING gin(to_tsvector('russian', tag_content))`,
 		`CREATE INDEX IF NOT EXISTS idx_articles_id_desc ON articles(id DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_articles_author_id ON articles(author_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_articles_created_at_id ON articles(created_at DESC, id DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_articles_num_views_id ON articles(num_views DESC, id DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_articles_publish_at ON articles(publish_at) WHERE publish_at IS NOT NULL`,
 	}
 	for _, sql := range indexes {
 		if err := DB.Exec(sql).Error; err != nil {
@@ -66,25 +86,25 @@ func createIndexForDB() error {
 	return nil
 }
 
-// func InitRedis() error {
-// 	if err := godotenv.Load("/root/.env"); err != nil {
-// 		log.Println("No .env file found, using system environment variables")
-// 	}
-// 	redisURL := os.Getenv("REDIS_URL")
-// 	log.Println(redisURL)
-
-// 	redisClient = redis.NewClient(&redis.Options{
-// 		Addr:     redisURL,
-// 		Password: "",
-// 		DB:       0,
-// 	})
+func InitRedis() error {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		redisURL = "redis:6379"
+	}
+	opts := &redis.Options{Addr: redisURL}
+	if strings.HasPrefix(redisURL, "redis://") || strings.HasPrefix(redisURL, "rediss://") {
+		var err error
+		if opts, err = redis.ParseURL(redisURL); err != nil {
+			return fmt.Errorf("failed to parse redis url: %w", err)
+		}
+	}
+	Redis = redis.NewClient(opts)
 
-// 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-// 	defer cancel()
-// 	if err := redisClient.Ping(ctx).Err(); err != nil {
-// 		log.Fatalf("Failed to connect to Redis: %v", err)
-// 		return err
-// 	}
-// 	log.Println("Successfully connected to Redis")
-// 	return nil
-// }
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Redis.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	log.Println("Successfully connected to Redis")
+	return nil
+}
@@ -0,0 +1,151 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksMaxAge — как долго ключи из JWKS используются без повторного запроса.
+	jwksMaxAge = time.Hour
+	// jwksMinRefresh ограничивает запросы JWKS, вызванные токенами с выдуманным kid. Интервал короткий,
+	// чтобы токены нового ключа, выданные сразу после очередного запроса, отвергались не дольше нескольких секунд.
+	jwksMinRefresh = 5 * time.Second
+)
+
+// JWK — открытый ключ Ed25519 в формате RFC 8037.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+}
+
+// JWKS — набор ключей, который публикуется на /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func newJWK(kid string, key ed25519.PublicKey) JWK {
+	return JWK{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		X:   base64.RawURLEncoding.EncodeToString(key),
+		Kid: kid,
+		Use: "sig",
+		Alg: "EdDSA",
+	}
+}
+
+func (k JWK) publicKey() (ed25519.PublicKey, error) {
+	if k.Kty != "OKP" || k.Crv != "Ed25519" {
+		return nil, fmt.Errorf("unsupported key type %s/%s", k.Kty, k.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil || len(x) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid key %s", k.Kid)
+	}
+	return ed25519.PublicKey(x), nil
+}
+
+// JWKSCache получает открытые ключи сервиса аутентификации по url и хранит их в памяти.
+// Ключи перезапрашиваются раз в jwksMaxAge, а также при встрече токена с неизвестным kid — так
+// новый ключ после ротации подхватывается сразу.
+type JWKSCache struct {
+	url    string
+	client *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]ed25519.PublicKey
+	fetchedAt time.Time
+
+	// fetchMu не даёт нескольким запросам одновременно обновлять ключи
+	fetchMu     sync.Mutex
+	lastAttempt time.Time
+}
+
+func NewJWKSCache(url string) *JWKSCache {
+	return &JWKSCache{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (c *JWKSCache) PublicKey(kid string) (crypto.PublicKey, error) {
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	stale := time.Since(c.fetchedAt) > jwksMaxAge
+	c.mu.RUnlock()
+	if ok && !stale {
+		return key, nil
+	}
+
+	if err := c.refresh(); err != nil {
+		log.Printf("error fetching JWKS: %s", err)
+		// Пока сервис аутентификации недоступен, уже известный ключ остаётся в силе
+		if ok {
+			return key, nil
+		}
+		return nil, ErrUnknownKey
+	}
+	c.mu.RLock()
+	key, ok = c.keys[kid]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+func (c *JWKSCache) refresh() error {
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+	if time.Since(c.lastAttempt) < jwksMinRefresh {
+		return nil
+	}
+	c.lastAttempt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var set JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("invalid JWKS: %w", err)
+	}
+	keys := make(map[string]ed25519.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Printf("skipping JWKS key: %s", err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
@@ -1,8 +1,10 @@
 package jwt
 
 import (
+	"crypto"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
-	"news/pkg/config"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -10,36 +12,96 @@ import (
 
 var (
 	ErrInvalidToken = errors.New("invalid token")
-	secretKey       = []byte(config.LoadConfig().JWTSecret)
+	ErrUnknownKey   = errors.New("unknown signing key")
+	ErrNoSigningKey = errors.New("no signing key configured")
 )
 
+// Signer выдаёт закрытый ключ для подписи новых токенов и его идентификатор kid.
+type Signer interface {
+	SigningKey() (kid string, key crypto.Signer)
+}
+
+// KeySource находит открытый ключ по kid из заголовка токена.
+type KeySource interface {
+	PublicKey(kid string) (crypto.PublicKey, error)
+}
+
+var (
+	// signer есть только у сервиса аутентификации; остальные сервисы токены лишь проверяют.
+	signer Signer
+	keys   KeySource
+)
+
+func SetSigner(s Signer) {
+	signer = s
+}
+
+func SetKeySource(source KeySource) {
+	keys = source
+}
+
+// TokenLifetime — срок действия access-токена. Сессия продлевается обменом refresh-токена.
+const TokenLifetime = 15 * time.Minute
+
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
+	// FamilyID — семейство refresh-токенов, при входе в которое выдан токен
+	FamilyID string `json:"sid,omitempty"`
+	// IssuedAtMilli — время выдачи в миллисекундах. iat хранит только секунды, а по нему нельзя отличить
+	// токен, выданный сразу после отзыва всех токенов пользователя, от выданного до него в ту же секунду.
+	IssuedAtMilli int64 `json:"iat_ms,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uint, username string) (string, error) {
+// newTokenID возвращает случайный идентификатор токена для claim jti.
+func newTokenID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func GenerateToken(userID uint, username, familyID string) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:        userID,
+		Username:      username,
+		FamilyID:      familyID,
+		IssuedAtMilli: now.UnixMilli(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(TokenLifetime)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secretKey)
+	if signer == nil {
+		return "", ErrNoSigningKey
+	}
+	kid, key := signer.SigningKey()
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = kid
+	return token.SignedString(key)
 }
 
+// ValidateToken проверяет подпись токена ключом, указанным в заголовке kid, и срок его действия.
 func ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
+		kid, ok := token.Header["kid"].(string)
+		if !ok || kid == "" {
+			return nil, ErrUnknownKey
+		}
+		if keys == nil {
+			return nil, ErrUnknownKey
 		}
-		return secretKey, nil
-	})
+		return keys.PublicKey(kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,209 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// retiredKeyTTL — сколько выведенный из оборота ключ ещё публикуется: за это время истекают все подписанные им токены.
+const retiredKeyTTL = 2 * TokenLifetime
+
+type signingKey struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+	CreatedAt  time.Time
+}
+
+// KeyManager хранит ключи Ed25519 сервиса аутентификации в каталоге dir, по файлу <kid>.pem на ключ,
+// и раз в rotateEvery создаёт новый. Новые токены подписываются последним ключом, а прежние
+// ключи публикуются в JWKS, пока не истекут подписанные ими токены.
+type KeyManager struct {
+	dir         string
+	rotateEvery time.Duration
+
+	mu   sync.RWMutex
+	keys []signingKey // от старых к новым
+}
+
+// NewKeyManager загружает ключи из dir и создаёт первый ключ, если их нет или последний устарел.
+func NewKeyManager(dir string, rotateEvery time.Duration) (*KeyManager, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	m := &KeyManager{dir: dir, rotateEvery: rotateEvery}
+	if err := m.rotateIfDue(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Run проверяет срок ротации ключей, пока не отменён ctx.
+func (m *KeyManager) Run(ctx context.Context) {
+	interval := min(m.rotateEvery, time.Hour)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.rotateIfDue(); err != nil {
+				log.Printf("error rotating signing keys: %s", err)
+			}
+		}
+	}
+}
+
+// rotateIfDue перечитывает каталог, чтобы увидеть ключи, созданные другими экземплярами сервиса,
+// создаёт новый ключ, если последний старше rotateEvery, и удаляет ключи, которые больше не нужны.
+func (m *KeyManager) rotateIfDue() error {
+	keys, err := m.load()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if len(keys) == 0 || now.Sub(keys[len(keys)-1].CreatedAt) >= m.rotateEvery {
+		key, err := m.generate(now)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		log.Printf("new signing key %s created", key.ID)
+	}
+
+	// Ключ выводится из оборота, когда появляется следующий
+	kept := keys[:0]
+	for i, key := range keys {
+		if i < len(keys)-1 && now.Sub(keys[i+1].CreatedAt) > retiredKeyTTL {
+			if err := os.Remove(m.keyPath(key.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("failed to remove signing key %s: %s", key.ID, err)
+			}
+			continue
+		}
+		kept = append(kept, key)
+	}
+
+	m.mu.Lock()
+	m.keys = kept
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *KeyManager) keyPath(kid string) string {
+	return filepath.Join(m.dir, kid+".pem")
+}
+
+func (m *KeyManager) load() ([]signingKey, error) {
+	paths, err := filepath.Glob(filepath.Join(m.dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	var keys []signingKey
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("invalid signing key %s", path)
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signing key %s: %w", path, err)
+		}
+		private, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("signing key %s is not Ed25519", path)
+		}
+		keys = append(keys, signingKey{
+			ID:         strings.TrimSuffix(filepath.Base(path), ".pem"),
+			PrivateKey: private,
+			CreatedAt:  info.ModTime(),
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// generate создаёт ключ и записывает его через временный файл, чтобы другой экземпляр не прочитал его наполовину.
+func (m *KeyManager) generate(now time.Time) (signingKey, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return signingKey{}, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	random := make([]byte, 4)
+	if _, err := rand.Read(random); err != nil {
+		return signingKey{}, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	kid := now.UTC().Format("20060102T150405") + "-" + hex.EncodeToString(random)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return signingKey{}, fmt.Errorf("failed to encode signing key: %w", err)
+	}
+	tmp, err := os.CreateTemp(m.dir, ".key-*")
+	if err != nil {
+		return signingKey{}, fmt.Errorf("failed to save signing key: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := pem.Encode(tmp, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		tmp.Close()
+		return signingKey{}, fmt.Errorf("failed to save signing key: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return signingKey{}, fmt.Errorf("failed to save signing key: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), m.keyPath(kid)); err != nil {
+		return signingKey{}, fmt.Errorf("failed to save signing key: %w", err)
+	}
+	return signingKey{ID: kid, PrivateKey: private, CreatedAt: now}, nil
+}
+
+// SigningKey возвращает последний ключ.
+func (m *KeyManager) SigningKey() (string, crypto.Signer) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key := m.keys[len(m.keys)-1]
+	return key.ID, key.PrivateKey
+}
+
+// PublicKey позволяет сервису аутентификации проверять токены своими ключами, не запрашивая JWKS.
+func (m *KeyManager) PublicKey(kid string) (crypto.PublicKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, key := range m.keys {
+		if key.ID == kid {
+			return key.PrivateKey.Public(), nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// JWKS возвращает открытые части всех действующих ключей.
+func (m *KeyManager) JWKS() JWKS {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	set := JWKS{Keys: make([]JWK, 0, len(m.keys))}
+	for _, key := range m.keys {
+		set.Keys = append(set.Keys, newJWK(key.ID, key.PrivateKey.Public().(ed25519.PublicKey)))
+	}
+	return set
+}
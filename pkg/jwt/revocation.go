@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrTokenRevoked = errors.New("token revoked")
+
+func revokedTokenKey(jti string) string {
+	return "jwt:revoked:" + jti
+}
+
+func revokedFamilyKey(familyID string) string {
+	return "jwt:revoked_family:" + familyID
+}
+
+func revokedBeforeKey(userID uint) string {
+	return "jwt:revoked_before:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// RevokeToken отзывает один токен. Запись в Redis живёт, пока токен не истечёт сам.
+func RevokeToken(ctx context.Context, rdb *redis.Client, claims *Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	return rdb.Set(ctx, revokedTokenKey(claims.ID), 1, ttl).Err()
+}
+
+// RevokeUserTokens отзывает все токены пользователя, выданные до текущего момента.
+// Хватает одной записи на пользователя: токены старше TokenLifetime истекают сами.
+func RevokeUserTokens(ctx context.Context, rdb *redis.Client, userID uint) error {
+	return rdb.Set(ctx, revokedBeforeKey(userID), time.Now().UnixMilli(), TokenLifetime).Err()
+}
+
+// RevokeTokenFamily отзывает access-токены, выданные по refresh-токенам семейства familyID.
+func RevokeTokenFamily(ctx context.Context, rdb *redis.Client, familyID string) error {
+	return rdb.Set(ctx, revokedFamilyKey(familyID), 1, TokenLifetime).Err()
+}
+
+// IsRevoked проверяет, отозван ли токен сам по себе, вместе со своим семейством или со всеми токенами пользователя.
+func IsRevoked(ctx context.Context, rdb *redis.Client, claims *Claims) (bool, error) {
+	keys := []string{revokedBeforeKey(claims.UserID)}
+	if claims.ID != "" {
+		keys = append(keys, revokedTokenKey(claims.ID))
+	}
+	if claims.FamilyID != "" {
+		keys = append(keys, revokedFamilyKey(claims.FamilyID))
+	}
+	values, err := rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return false, err
+	}
+	return revokedBy(claims, values)
+}
+
+// revokedBy решает, отозван ли токен, по значениям ключей из IsRevoked: первым идёт время отзыва
+// всех токенов пользователя, за ним записи об отзыве самого токена и его семейства.
+func revokedBy(claims *Claims, values []interface{}) (bool, error) {
+	for _, value := range values[1:] {
+		if value != nil {
+			return true, nil
+		}
+	}
+	if values[0] == nil {
+		return false, nil
+	}
+	revokedBefore, err := strconv.ParseInt(values[0].(string), 10, 64)
+	if err != nil {
+		return false, err
+	}
+	return issuedAtMilli(claims) < revokedBefore, nil
+}
+
+// issuedAtMilli возвращает время выдачи токена в миллисекундах. У токенов без iat_ms берётся начало
+// секунды из iat, поэтому выданный в ту же секунду до отзыва токен всё равно считается отозванным.
+func issuedAtMilli(claims *Claims) int64 {
+	if claims.IssuedAtMilli != 0 {
+		return claims.IssuedAtMilli
+	}
+	if claims.IssuedAt == nil {
+		return 0
+	}
+	return claims.IssuedAt.Unix() * 1000
+}
@@ -0,0 +1,75 @@
+package jwt
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRevokedBy(t *testing.T) {
+	issued := time.Date(2026, 5, 1, 12, 0, 0, 250_000_000, time.UTC)
+	millis := func(t time.Time) interface{} { return strconv.FormatInt(t.UnixMilli(), 10) }
+	withMilli := &Claims{IssuedAtMilli: issued.UnixMilli(), RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(issued)}}
+	secondsOnly := &Claims{RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(issued)}}
+	tests := []struct {
+		name    string
+		claims  *Claims
+		values  []interface{}
+		want    bool
+		wantErr bool
+	}{
+		{"ничего не отозвано", withMilli, []interface{}{nil, nil, nil}, false, false},
+		{"отозван сам токен", withMilli, []interface{}{nil, "1", nil}, true, false},
+		{"отозвано семейство", withMilli, []interface{}{nil, nil, "1"}, true, false},
+		{"отзыв после выдачи", withMilli, []interface{}{millis(issued.Add(time.Millisecond))}, true, false},
+		{"отзыв в момент выдачи", withMilli, []interface{}{millis(issued)}, false, false},
+		{"выдан после отзыва в ту же секунду", withMilli, []interface{}{millis(issued.Add(-100 * time.Millisecond))}, false, false},
+		{"без iat_ms выдан в секунду отзыва", secondsOnly, []interface{}{millis(issued.Add(-100 * time.Millisecond))}, true, false},
+		{"без iat_ms выдан в секунду до отзыва", secondsOnly, []interface{}{millis(issued.Add(-time.Second))}, false, false},
+		{"без времени выдачи", &Claims{}, []interface{}{millis(issued)}, true, false},
+		{"повреждённое значение", withMilli, []interface{}{"abc"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := revokedBy(tt.claims, tt.values)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("revokedBy = %v, %v, want %v, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateTokenIssuedAtMilli(t *testing.T) {
+	manager, err := NewKeyManager(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetSigner(manager)
+	SetKeySource(manager)
+	t.Cleanup(func() {
+		SetSigner(nil)
+		SetKeySource(nil)
+	})
+
+	before := time.Now().UnixMilli()
+	token, err := GenerateToken(7, "user", "family")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != 7 || claims.FamilyID != "family" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if claims.IssuedAtMilli < before || claims.IssuedAtMilli/1000 != claims.IssuedAt.Unix() {
+		t.Errorf("iat_ms = %d, iat = %d, выдан не раньше %d", claims.IssuedAtMilli, claims.IssuedAt.Unix(), before)
+	}
+	revoked, err := revokedBy(claims, []interface{}{strconv.FormatInt(claims.IssuedAtMilli, 10)})
+	if err != nil || revoked {
+		t.Errorf("токен, выданный в миллисекунду отзыва, отозван: %v, %v", revoked, err)
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"news/pkg/config"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequireAdmin пропускает только пользователей из списка ADMIN_USERNAMES (имена через запятую).
+// Должен стоять после JWTAuth или JWTAuthAPI, которые кладут имя пользователя в контекст.
+func RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	admins := make(map[string]bool)
+	for _, name := range strings.Split(config.GetEnv("ADMIN_USERNAMES", ""), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			admins[name] = true
+		}
+	}
+	return func(c echo.Context) error {
+		username, _ := c.Get("username").(string)
+		if !admins[username] {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "admin access required"})
+		}
+		return next(c)
+	}
+}
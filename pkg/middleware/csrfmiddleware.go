@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+)
+
+// CSRFContextKey — ключ контекста, под которым лежит CSRF-токен для скрытого поля _csrf в формах.
+const CSRFContextKey = "csrf"
+
+// CSRF защищает формы, которые браузер отправляет с cookie jwt: токен из cookie _csrf должен совпасть
+// с полем _csrf формы или заголовком X-CSRF-Token. Запросы без cookie jwt (клиенты с Bearer-токеном)
+// и JSON-запросы, которые чужой сайт не может отправить без CORS, не проверяются.
+// Должен стоять после JWTAuth.
+var CSRF = echomiddleware.CSRFWithConfig(echomiddleware.CSRFConfig{
+	Skipper: func(c echo.Context) bool {
+		if cookie, err := c.Cookie("jwt"); err != nil || cookie.Value == "" {
+			return true
+		}
+		return strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON)
+	},
+	TokenLookup:    "form:_csrf,header:" + echo.HeaderXCSRFToken,
+	ContextKey:     CSRFContextKey,
+	CookiePath:     "/",
+	CookieHTTPOnly: true,
+	CookieSameSite: http.SameSiteLaxMode,
+	ErrorHandler: func(err error, c echo.Context) error {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "invalid csrf token"})
+	},
+})
@@ -1,25 +1,78 @@
 package middleware
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
 	"news/pkg/jwt"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
 )
 
+// revocationStore хранит список отозванных токенов. Без него отзыв не проверяется.
+var revocationStore *redis.Client
+
+func SetRevocationStore(client *redis.Client) {
+	revocationStore = client
+}
+
+// ErrRevocationUnavailable означает, что список отозванных токенов недоступен и токен нельзя принять.
+var ErrRevocationUnavailable = errors.New("не удалось проверить, отозван ли токен")
+
+// validateToken проверяет подпись и срок действия токена и то, что он не отозван.
+// Если Redis недоступен, токен отклоняется с ErrRevocationUnavailable: иначе на время сбоя
+// снова заработали бы токены, отозванные при выходе.
+func validateToken(c echo.Context, tokenString string) (*jwt.Claims, error) {
+	claims, err := jwt.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if revocationStore == nil {
+		return claims, nil
+	}
+	revoked, err := jwt.IsRevoked(c.Request().Context(), revocationStore, claims)
+	if err != nil {
+		log.Printf("error checking token revocation: %s", err)
+		return nil, ErrRevocationUnavailable
+	}
+	if revoked {
+		return nil, jwt.ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// RefreshCookie — имя cookie с refresh-токеном.
+const RefreshCookie = "refresh_token"
+
+// loginRedirect отправляет пользователя без действительного access-токена на страницу входа.
+// Если у браузера есть refresh-токен, GET-запрос сначала перенаправляется на его обмен
+// с возвратом на ту же страницу.
+func loginRedirect(c echo.Context) error {
+	if refresh, err := c.Cookie(RefreshCookie); err == nil && refresh.Value != "" && c.Request().Method == http.MethodGet {
+		return c.Redirect(http.StatusSeeOther, "/token/refresh?next="+url.QueryEscape(c.Request().URL.RequestURI()))
+	}
+	return c.Redirect(http.StatusSeeOther, "/login-page")
+}
+
 func JWTAuth(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		cookie, err := c.Cookie("jwt")
 		if err != nil {
-			return c.Redirect(http.StatusSeeOther, "/login-page")
+			return loginRedirect(c)
 		}
 		tokenstring := cookie.Value
 		if tokenstring == "" {
-			return c.Redirect(http.StatusSeeOther, "/login-page")
+			return loginRedirect(c)
+		}
+		claims, err := validateToken(c, tokenstring)
+		if errors.Is(err, ErrRevocationUnavailable) {
+			// Токен может быть действительным: не сбрасываем его и не отправляем на обмен, чтобы не зациклить перенаправления
+			return c.String(http.StatusServiceUnavailable, "Сервис временно недоступен, попробуйте позже")
 		}
-		claims, err := jwt.ValidateToken(tokenstring)
 		if err != nil {
 			cookie := new(http.Cookie)
 			cookie.Name = "jwt"
@@ -27,7 +80,7 @@ func JWTAuth(next echo.HandlerFunc) echo.HandlerFunc {
 			cookie.Expires = time.Now().Add(24 * time.Hour)
 			cookie.Path = "/"
 			c.SetCookie(cookie)
-			return c.Redirect(http.StatusSeeOther, "/login-page")
+			return loginRedirect(c)
 		}
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
@@ -36,13 +89,69 @@ func JWTAuth(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
+// JWTOptional пропускает анонимные запросы к публичным страницам, а пользователю с действительным
+// токеном проставляет userID, как JWTAuth. Браузер с истёкшим access-токеном, но с refresh-токеном,
+// сначала отправляется на обмен токена, чтобы не увидеть страницу как аноним.
+func JWTOptional(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tokenString, err := TokenFromRequest(c)
+		if err == nil {
+			claims, err := validateToken(c, tokenString)
+			if err == nil {
+				c.Set("userID", claims.UserID)
+				c.Set("username", claims.Username)
+				return next(c)
+			}
+			if errors.Is(err, ErrRevocationUnavailable) {
+				// Обмен токена не поможет, пока Redis недоступен: страница показывается как анониму
+				return next(c)
+			}
+		}
+		if refresh, err := c.Cookie(RefreshCookie); err == nil && refresh.Value != "" && c.Request().Method == http.MethodGet {
+			return loginRedirect(c)
+		}
+		return next(c)
+	}
+}
+
+// JWTAuthAPI проверяет токен так же, как JWTAuth, но вместо перенаправления на страницу входа отвечает 401 в JSON.
+func JWTAuthAPI(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tokenString, err := TokenFromRequest(c)
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "authentication required"})
+		}
+		claims, err := validateToken(c, tokenString)
+		if errors.Is(err, ErrRevocationUnavailable) {
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "token revocation check unavailable"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		}
+		c.Set("userID", claims.UserID)
+		c.Set("username", claims.Username)
+		return next(c)
+	}
+}
+
+// TokenFromRequest возвращает JWT из cookie jwt или из заголовка Authorization: Bearer.
+func TokenFromRequest(c echo.Context) (string, error) {
+	if cookie, err := c.Cookie("jwt"); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+	auth := c.Request().Header.Get(echo.HeaderAuthorization)
+	if token, ok := strings.CutPrefix(auth, "Bearer "); ok && token != "" {
+		return token, nil
+	}
+	return "", http.ErrNoCookie
+}
+
 func GetUserIDFromToken(c echo.Context) (uint, error) {
-	cookie, err := c.Cookie("jwt")
+	tokenString, err := TokenFromRequest(c)
 	if err != nil {
 		return 0, err
 	}
-	log.Printf("coockie for jwt from func: %s", cookie)
-	token, err := jwt.ValidateToken(cookie.Value)
+	token, err := validateToken(c, tokenString)
 	if err != nil {
 		return 0, err
 	}
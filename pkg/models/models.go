@@ -1,6 +1,9 @@
 package models
 
 import (
+	"strconv"
+	"time"
+
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -9,6 +12,7 @@ type User struct {
 	gorm.Model
 	Username     string    `gorm:"type:varchar(50);not null;unique" json:"username"`
 	PasswordHash string    `gorm:"type:varchar(100);not null" json:"-"`
+	Email        *string   `gorm:"type:varchar(255);uniqueIndex" json:"-"`
 	Articles     []Article `gorm:"foreignKey:AuthorID" json:"articles,omitempty"`
 }
 
@@ -29,6 +33,61 @@ func (User) TableName() string {
 	return "users"
 }
 
+// PasswordResetToken — одноразовый токен восстановления пароля. Хранится только SHA-256 хеш токена.
+type PasswordResetToken struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"type:char(64);not null;unique" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	User      User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// Session — один вход пользователя, то есть одно семейство refresh-токенов FamilyID.
+// Отзыв сессии отзывает всё семейство.
+type Session struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID   string     `gorm:"type:varchar(64);not null;unique" json:"-"`
+	UserAgent  string     `gorm:"type:varchar(255)" json:"user_agent"`
+	IP         string     `gorm:"type:varchar(64)" json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `gorm:"not null" json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	User       User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// RefreshToken — долгоживущий refresh-токен. Хранится только SHA-256 хеш токена.
+// Токены одного входа образуют семейство FamilyID: при каждом обмене выдаётся новый токен
+// того же семейства, а старый помечается использованным.
+type RefreshToken struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID  string     `gorm:"type:varchar(64);not null;index" json:"family_id"`
+	TokenHash string     `gorm:"type:char(64);not null;unique" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	// Successor — выданный при обмене следующий токен, зашифрованный ключом из этого токена.
+	// Его получает параллельный запрос, повторно предъявивший токен в пределах короткого окна.
+	Successor string     `gorm:"type:text" json:"-"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	User      User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
 type Tag struct {
 	gorm.Model
 	TagContent string    `gorm:"type:varchar(50);not null;unique" json:"tagContent"`
@@ -39,16 +98,191 @@ func (Tag) TableName() string {
 	return "tags"
 }
 
+// TagAlias — альтернативное имя тега, например "golang" для "go".
+type TagAlias struct {
+	ID    uint   `gorm:"primarykey" json:"id"`
+	Alias string `gorm:"type:varchar(50);not null;unique" json:"alias"`
+	TagID uint   `gorm:"not null;index" json:"tag_id"`
+	Tag   Tag    `gorm:"foreignKey:TagID;constraint:OnDelete:CASCADE;" json:"tag"`
+}
+
+func (TagAlias) TableName() string {
+	return "tag_aliases"
+}
+
+const (
+	ArticleStatusDraft     = "draft"
+	ArticleStatusInReview  = "in_review"
+	ArticleStatusPublished = "published"
+	ArticleStatusArchived  = "archived"
+	// ArticleStatusScheduled — статья одобрена автором к публикации и ждёт времени PublishAt
+	ArticleStatusScheduled = "scheduled"
+)
+
 type Article struct {
 	gorm.Model
 	AuthorID       uint   `gorm:"not null" json:"author_id"`
 	ArticleTitle   string `gorm:"type:text;not null" json:"article_title"`
+	Slug           string `gorm:"type:varchar(120);uniqueIndex" json:"slug"`
 	ArticleContent string `gorm:"type:text;not null" json:"article_content"`
-	NumViews       int    `gorm:"default:0" json:"num_views"`
-	Author         User   `gorm:"foreignKey:AuthorID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"author"`
-	Tags           []Tag  `gorm:"many2many:article_tags;" json:"tags,omitempty"`
+	// ContentHTML — ArticleContent, переведённый из Markdown в очищенный HTML при сохранении
+	ContentHTML string     `gorm:"type:text" json:"content_html"`
+	Status      string     `gorm:"type:varchar(20);not null;default:'published';index" json:"status"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	// PublishedAt — время первой публикации статьи, вручную или по расписанию
+	PublishedAt *time.Time `gorm:"index" json:"published_at,omitempty"`
+	NumViews    int        `gorm:"default:0" json:"num_views"`
+	Author      User       `gorm:"foreignKey:AuthorID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"author"`
+	Tags        []Tag      `gorm:"many2many:article_tags;" json:"tags,omitempty"`
+	// Reactions — число реакций каждого вида, заполняется сервисом статей
+	Reactions map[string]int64 `gorm:"-" json:"reactions,omitempty"`
 }
 
 func (Article) TableName() string {
 	return "articles"
 }
+
+// URLPath возвращает адрес страницы статьи: по slug, а для статей без slug — по ID.
+func (a Article) URLPath() string {
+	if a.Slug != "" {
+		return "/article/" + a.Slug
+	}
+	return "/article/" + strconv.FormatUint(uint64(a.ID), 10)
+}
+
+// ArticleSlug — прежний slug статьи. Старые адреса перенаправляются на текущий slug после смены заголовка.
+type ArticleSlug struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Slug      string    `gorm:"type:varchar(120);not null;unique" json:"slug"`
+	ArticleID uint      `gorm:"not null;index" json:"article_id"`
+	Article   Article   `gorm:"foreignKey:ArticleID;constraint:OnDelete:CASCADE;" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (ArticleSlug) TableName() string {
+	return "article_slugs"
+}
+
+type ArticleRevision struct {
+	gorm.Model
+	ArticleID      uint   `gorm:"not null;index;uniqueIndex:idx_article_revision_version" json:"article_id"`
+	Version        int    `gorm:"not null;uniqueIndex:idx_article_revision_version" json:"version"`
+	EditorID       uint   `gorm:"not null" json:"editor_id"`
+	ArticleTitle   string `gorm:"type:text;not null" json:"article_title"`
+	ArticleContent string `gorm:"type:text;not null" json:"article_content"`
+	Tags           string `gorm:"type:text" json:"tags"`
+	Editor         User   `gorm:"foreignKey:EditorID" json:"editor"`
+}
+
+func (ArticleRevision) TableName() string {
+	return "article_revisions"
+}
+
+type Comment struct {
+	gorm.Model
+	ArticleID uint   `gorm:"not null;index" json:"article_id"`
+	AuthorID  uint   `gorm:"not null" json:"author_id"`
+	ParentID  *uint  `gorm:"index" json:"parent_id,omitempty"`
+	Body      string `gorm:"type:text;not null" json:"body"`
+	Hidden    bool   `gorm:"not null;default:false" json:"hidden"`
+	Author    User   `gorm:"foreignKey:AuthorID" json:"author"`
+}
+
+func (Comment) TableName() string {
+	return "comments"
+}
+
+const (
+	ReactionLike    = "like"
+	ReactionDislike = "dislike"
+	ReactionLove    = "love"
+	ReactionLaugh   = "laugh"
+	ReactionWow     = "wow"
+	ReactionSad     = "sad"
+)
+
+// ArticleReaction — реакция пользователя на статью. Каждый вид реакции пользователь может поставить статье один раз.
+type ArticleReaction struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	ArticleID uint      `gorm:"not null;uniqueIndex:idx_article_reactions_unique" json:"article_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_article_reactions_unique;index" json:"user_id"`
+	Kind      string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_article_reactions_unique" json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (ArticleReaction) TableName() string {
+	return "article_reactions"
+}
+
+// Bookmark — статья, сохранённая пользователем в список для чтения.
+type Bookmark struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_bookmarks_unique" json:"user_id"`
+	ArticleID uint      `gorm:"not null;uniqueIndex:idx_bookmarks_unique;index" json:"article_id"`
+	CreatedAt time.Time `json:"created_at"`
+	User      User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+	Article   Article   `gorm:"foreignKey:ArticleID;constraint:OnDelete:CASCADE;" json:"article"`
+}
+
+func (Bookmark) TableName() string {
+	return "bookmarks"
+}
+
+// Attachment — изображение, загруженное автором. Файлы лежат в хранилище по ключам StorageKey и Thumbnails.
+type Attachment struct {
+	gorm.Model
+	UploaderID  uint   `gorm:"not null;index" json:"uploader_id"`
+	ArticleID   *uint  `gorm:"index" json:"article_id,omitempty"`
+	StorageKey  string `gorm:"type:varchar(255);not null;unique" json:"storage_key"`
+	ContentType string `gorm:"type:varchar(50);not null" json:"content_type"`
+	Size        int64  `gorm:"not null" json:"size"`
+	Width       int    `gorm:"not null" json:"width"`
+	Height      int    `gorm:"not null" json:"height"`
+	// Thumbnails — ключи уменьшенных копий по ширине в пикселях
+	Thumbnails map[int]string `gorm:"type:jsonb;serializer:json" json:"thumbnails"`
+	Uploader   User           `gorm:"foreignKey:UploaderID;constraint:OnDelete:CASCADE;" json:"-"`
+	Article    *Article       `gorm:"foreignKey:ArticleID;constraint:OnDelete:SET NULL;" json:"-"`
+}
+
+func (Attachment) TableName() string {
+	return "attachments"
+}
+
+// Follow — подписка пользователя на автора.
+type Follow struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	FollowerID uint      `gorm:"not null;uniqueIndex:idx_follows_unique" json:"follower_id"`
+	AuthorID   uint      `gorm:"not null;uniqueIndex:idx_follows_unique;index" json:"author_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Follower   User      `gorm:"foreignKey:FollowerID;constraint:OnDelete:CASCADE;" json:"-"`
+	Author     User      `gorm:"foreignKey:AuthorID;constraint:OnDelete:CASCADE;" json:"author"`
+}
+
+func (Follow) TableName() string {
+	return "follows"
+}
+
+// TagFollow — подписка пользователя на тег.
+type TagFollow struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_tag_follows_unique" json:"user_id"`
+	TagID     uint      `gorm:"not null;uniqueIndex:idx_tag_follows_unique;index" json:"tag_id"`
+	CreatedAt time.Time `json:"created_at"`
+	User      User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+	Tag       Tag       `gorm:"foreignKey:TagID;constraint:OnDelete:CASCADE;" json:"tag"`
+}
+
+func (TagFollow) TableName() string {
+	return "tag_follows"
+}
+
+// ViewFlushBatch — пачка просмотров, перенесённая из Redis в articles.num_views. Запись создаётся
+// в той же транзакции, что и обновление счётчиков, поэтому повторный перенос пачки ничего не меняет.
+type ViewFlushBatch struct {
+	ID        string    `gorm:"type:varchar(64);primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (ViewFlushBatch) TableName() string {
+	return "view_flush_batches"
+}
@@ -0,0 +1,19 @@
+// Package storage хранит загруженные файлы. Сервисы работают с интерфейсом Storage,
+// поэтому локальный диск можно заменить S3-совместимым хранилищем без изменения обработчиков.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrInvalidKey = errors.New("недопустимый ключ файла")
+
+// Storage сохраняет и удаляет файлы по ключу вида images/2025/01/abc.jpg.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Delete(ctx context.Context, key string) error
+	// URL возвращает адрес, по которому файл доступен клиентам.
+	URL(key string) string
+}